@@ -0,0 +1,292 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	anyMapType    = reflect.TypeOf(map[string]interface{}(nil))
+)
+
+// Reports whether x points to an interface{} or map[string]interface{},
+// which are decoded without a schema.
+func isSchemaless(x interface{}) bool {
+	v := reflect.ValueOf(x)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return false
+	}
+	t := v.Type().Elem()
+	return t == interfaceType || t == anyMapType
+}
+
+// An anyOverride holds the keys of a profile section until the whole
+// file is read, so they win wherever the section appears.
+type anyOverride struct {
+	section sectionHeader
+	values  map[string]interface{}
+}
+
+// An anyPath names a value by the path of the section holding it.
+type anyPath struct {
+	section string
+	name    string
+}
+
+// noSection is the path of a map holding no sections, like the keys of
+// a profile section, checked against the section they override later.
+const noSection = "\x00"
+
+/*
+ * An anyBinder decodes a file without a schema into maps: top level
+ * keys and sections go in the root map, each section is a map of its
+ * keys, a repeated key is a []interface{} of its values and a repeated
+ * section a []interface{} of its maps. A key like limits[cpu] is an
+ * entry of a map named limits. Names are folded the way the dialect
+ * matches them. A key and a section of the same name, or a key set both
+ * with and without an index, is an error.
+ */
+type anyBinder struct {
+	d         *decodeState
+	x         interface{}
+	root      map[string]interface{}
+	cur       map[string]interface{} // map of the current section
+	path      string                 // path of the current section
+	inherited map[string]bool        // keys of cur set by fallbacks
+	entered   map[string]bool        // section paths a header entered
+	sections  map[anyPath]bool       // values that are sections
+	overrides []anyOverride
+}
+
+func (d *decodeState) newAnyBinder(x interface{}) *anyBinder {
+	root := make(map[string]interface{})
+	return &anyBinder{d: d, x: x, root: root, cur: root, inherited: make(map[string]bool),
+		entered: make(map[string]bool), sections: make(map[anyPath]bool)}
+}
+
+func (b *anyBinder) key(ev event) bool {
+	if ev.section.skipped() || b.d.isExtends(ev.name) {
+		return true
+	}
+	if b.inherited[ev.name] {
+		delete(b.cur, ev.name)
+		delete(b.inherited, ev.name)
+	}
+	b.add(b.cur, b.path, ev)
+	return true
+}
+
+func (b *anyBinder) header(ev event) bool {
+	d := b.d
+	b.inherited = make(map[string]bool)
+	if ev.section.skipped() {
+		return true
+	} else if ev.section.active {
+		b.cur, b.path = make(map[string]interface{}), noSection
+		b.overrides = append(b.overrides, anyOverride{ev.section, b.cur})
+		return true
+	}
+
+	b.cur, b.path = b.section(ev.section, b.entered)
+	d.trace(TraceEvent{Kind: TraceHeader})
+	b.fill(ev.section)
+	return true
+}
+
+// Applies the profile overrides and stores the root map in x.
+func (b *anyBinder) finish() error {
+	for _, o := range b.overrides {
+		m, path := b.root, ""
+		if len(o.section.name) > 0 {
+			m, path = b.section(o.section, nil)
+		}
+		for k, v := range o.values {
+			_, isMap := v.(map[string]interface{})
+			if _, wasMap := m[k].(map[string]interface{}); b.sections[anyPath{path, k}] || (m[k] != nil && isMap != wasMap) {
+				b.d.saveError(&IniError{o.section.lineNum, o.section.line, "Conflicting values of " + k})
+				break
+			}
+			m[k] = v
+		}
+	}
+	if b.d.savedError != nil {
+		return b.d.savedError
+	}
+
+	v := reflect.ValueOf(b.x).Elem()
+	if v.Type() == anyMapType && !v.IsNil() {
+		for k, value := range b.root {
+			v.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(value))
+		}
+	} else {
+		v.Set(reflect.ValueOf(b.root))
+	}
+	return nil
+}
+
+/*
+ * Returns the map of a section and its path, found by its dotted path
+ * when dotted sections are on. For a header, entered holds the paths of
+ * the sections headers entered so far: a section entered again starts a
+ * new map, turning it into a list of maps, while one only created as
+ * part of a longer path, like server by [server.tls], is entered as it
+ * is. A key of the same name as a section along the path is an error.
+ */
+func (b *anyBinder) section(header sectionHeader, entered map[string]bool) (map[string]interface{}, string) {
+	d := b.d
+	path := []string{header.name}
+	if d.dotted || d.dialect.Subsections {
+		path = strings.Split(header.name, ".")
+	}
+
+	m, key := b.root, ""
+	for i, name := range path {
+		name = strings.TrimSpace(name)
+		at := anyPath{key, name}
+		if i > 0 {
+			key += "."
+		}
+		key += name
+		repeat := i == len(path)-1 && entered[key]
+
+		if m[name] != nil && !b.sections[at] {
+			d.saveError(&IniError{header.lineNum, header.line, fmt.Sprintf("Section [%s] conflicts with key %s", header.name, name)})
+			return make(map[string]interface{}), noSection
+		}
+		b.sections[at] = true
+
+		var next map[string]interface{}
+		switch old := m[name].(type) {
+		case map[string]interface{}:
+			next = old
+			if repeat {
+				next = make(map[string]interface{})
+				m[name] = []interface{}{old, next}
+			}
+		case []interface{}:
+			next = old[len(old)-1].(map[string]interface{})
+			if repeat {
+				next = make(map[string]interface{})
+				m[name] = append(old, next)
+			}
+		}
+
+		if repeat {
+			// the sections of the new map are entered afresh
+			for k := range entered {
+				if strings.HasPrefix(k, key+".") {
+					delete(entered, k)
+				}
+			}
+		}
+
+		if next == nil {
+			next = make(map[string]interface{})
+			m[name] = next
+		}
+		m = next
+	}
+
+	if entered != nil {
+		entered[key] = true
+	}
+	return m, key
+}
+
+/*
+ * Adds a key to the map of the section at path. A key that is set
+ * again, or that ends in [], becomes a list. A key naming a section, or
+ * a map of entries set with an index and also without one, is an error.
+ */
+func (b *anyBinder) add(m map[string]interface{}, path string, ev event) {
+	d := b.d
+	value := d.anyValue(ev.value)
+	d.trace(TraceEvent{Kind: TraceSet, Key: ev.name, Value: ev.value})
+
+	old := m[ev.name]
+	_, isMap := old.(map[string]interface{})
+	conflict := ""
+	switch {
+	case b.sections[anyPath{path, ev.name}]:
+		conflict = "section [" + ev.name + "]"
+	case old != nil && isMap && len(ev.index) == 0:
+		conflict = "map " + ev.name
+	case old != nil && !isMap && len(ev.index) > 0:
+		conflict = "key " + ev.name
+	}
+	if len(conflict) > 0 {
+		d.saveError(&IniError{ev.lineNum, ev.line, fmt.Sprintf("Key %s conflicts with %s", ev.key, conflict)})
+		return
+	}
+
+	if len(ev.index) > 0 {
+		entries, ok := old.(map[string]interface{})
+		if !ok {
+			entries = make(map[string]interface{})
+			m[ev.name] = entries
+		}
+		entries[ev.index] = value
+		return
+	}
+
+	switch old := old.(type) {
+	case nil:
+		if ev.list {
+			m[ev.name] = []interface{}{value}
+		} else {
+			m[ev.name] = value
+		}
+	case []interface{}:
+		m[ev.name] = append(old, value)
+	default:
+		m[ev.name] = []interface{}{old, value}
+	}
+}
+
+/*
+ * Fills a section that was just entered from the sections it falls back
+ * on, like applyFallbacks does for structs. Every key set is marked in
+ * inherited, so a key of the section itself replaces it.
+ */
+func (b *anyBinder) fill(section sectionHeader) {
+	for _, layer := range b.d.fallbacks(section) {
+		set := make(map[string]bool)
+		for _, kv := range layer {
+			if !set[kv.name] {
+				delete(b.cur, kv.name)
+				set[kv.name] = true
+			}
+			b.add(b.cur, b.path, kv)
+			b.inherited[kv.name] = true
+		}
+	}
+}
+
+// Returns a value as a string, or as the bool, int64 or float64 it
+// holds when types are inferred.
+func (d *decodeState) anyValue(s string) interface{} {
+	if !d.inferTypes {
+		return s
+	}
+	return inferValue(s)
+}
+
+// Returns the bool, int64 or float64 a string holds, or the string.
+func inferValue(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	} else if f, err := strconv.ParseFloat(s, 64); err == nil && strings.ContainsAny(s, "0123456789") {
+		return f // not Inf or NaN
+	}
+
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true
+	case "false", "no", "off":
+		return false
+	}
+	return s
+}
@@ -0,0 +1,242 @@
+package ini
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Scoping selects how headers and keys are matched to the sections
+// being decoded, when the default rule doesn't fit a file. Flags can
+// be combined.
+//
+// The default rule: a header is looked up in the current section,
+// then in each section enclosing it, outwards to the top level. The
+// first section that has it is entered, and the sections inside that
+// one are left. A key only sets a field of the current section. A
+// header that no section has returns to the top level, so the keys
+// following it are matched against top level fields.
+type Scoping int
+
+const (
+	// ScopeOutwardKeys matches a key that is not a field of the current
+	// section against the sections enclosing it, outwards to the top
+	// level, so a top level key may follow a section.
+	ScopeOutwardKeys Scoping = 1 << iota
+
+	// ScopeSkipUnknown leaves the keys following a header that no
+	// section has unmatched, instead of matching them at the top level.
+	ScopeSkipUnknown
+)
+
+/*
+ * An event is a line of the file as the parse stage, decodeState.next,
+ * reads it, with the section it belongs to. Binders only read events,
+ * so what a line means is decided in one place.
+ */
+type event struct {
+	kind    lineKind
+	name    string // folded key without its index, or [header] path
+	key     string // folded key as written, index included
+	value   string // unquoted value
+	raw     string // value before unquoting, see RawValue
+	index   string // name inside the brackets of a key like limits[cpu]
+	list    bool   // key ended in [], like extension[]
+	lineNum int
+	line    string
+	section sectionHeader
+}
+
+// A sectionHeader is the header of the section a line belongs to,
+// zero at the top level.
+type sectionHeader struct {
+	name    string // without brackets, profile or base
+	lineNum int
+	line    string
+	profile string // profile of a header like [NAME@profile]
+	active  bool   // profile is the one decoded, see Decoder.Profile
+	base    string // base of a header like [prod : base]
+}
+
+// Reports whether the section is of a profile other than the one
+// decoded, and is skipped.
+func (s sectionHeader) skipped() bool {
+	return len(s.profile) > 0 && !s.active
+}
+
+/*
+ * A binder maps the events of a file onto a value. It reports whether
+ * each key and header matched, and finishes the value at the end of the
+ * file.
+ */
+type binder interface {
+	key(ev event) bool
+	header(ev event) bool
+	finish() error
+}
+
+// Reads every line of the file and binds it with b, stopping at the
+// first error.
+func (d *decodeState) bind(b binder) error {
+	for {
+		ev, ok := d.next()
+		if !ok || d.savedError != nil {
+			break
+		}
+
+		matched := true
+		switch ev.kind {
+		case lineKeyValue:
+			matched = b.key(ev)
+		case lineHeader:
+			matched = b.header(ev)
+		case lineBare:
+			matched = false
+		}
+
+		if !matched {
+			d.unmatchedLine(ev)
+		}
+	}
+
+	if d.savedError != nil {
+		return d.savedError
+	}
+	return b.finish()
+}
+
+// A structBinder sets the fields of a struct. The stack holds the
+// property maps of the sections entered, innermost on top.
+type structBinder struct {
+	d       *decodeState
+	top     propertyMap
+	stack   *PropMapStack
+	unknown bool // in a section no header matched, see ScopeSkipUnknown
+	skip    bool // in a section of another profile, or an ignored one
+}
+
+func (d *decodeState) newStructBinder(x interface{}) *structBinder {
+	b := &structBinder{d: d, top: make(propertyMap), stack: NewPropMapStack()}
+	d.generateMap(b.top, reflect.ValueOf(x))
+	b.stack.Push(b.top)
+	return b
+}
+
+func (b *structBinder) key(ev event) bool {
+	d := b.d
+	if d.isExtends(ev.name) {
+		return true // base section, read ahead by prescan
+	} else if b.skip {
+		return true // section of another profile, or ignored
+	} else if b.unknown {
+		return false
+	}
+
+	if prop, ev, ok := keyProperty(b.lookupKey, ev); ok {
+		d.setProperty(prop, ev)
+		return true
+	}
+	return d.isFallbackSection(ev.section)
+}
+
+/*
+ * Finds the property of a key event with lookup. A key like limits[cpu]
+ * or extension[] sets a map or slice named by the part before the
+ * brackets. Other fields are found by the key as written, brackets
+ * included, which is then returned as the name of the event.
+ */
+func keyProperty(lookup func(string) (property, bool), ev event) (property, event, bool) {
+	if ev.name != ev.key {
+		if prop, ok := lookup(ev.name); ok && !prop.isSection && (prop.isMap || prop.isArray) {
+			return prop, ev, true
+		}
+		ev.name, ev.index, ev.list = ev.key, "", false
+	}
+	prop, ok := lookup(ev.name)
+	return prop, ev, ok
+}
+
+// Finds the property of a key in the current section, or in the
+// sections enclosing it with ScopeOutwardKeys.
+func (b *structBinder) lookupKey(name string) (property, bool) {
+	top := b.stack.Size() - 1
+	for i := top; i >= 0; i-- {
+		if prop := b.stack.Index(i)[name]; prop.isInitialized && (i == top || !prop.isSection) {
+			return prop, true
+		}
+		if b.d.scoping&ScopeOutwardKeys == 0 {
+			break
+		}
+	}
+	return property{}, false
+}
+
+func (b *structBinder) header(ev event) bool {
+	d := b.d
+	b.unknown, b.skip = false, ev.section.skipped()
+	d.inherited = nil
+	if b.skip || (ev.section.active && len(ev.section.name) == 0) {
+		// ignore another profile, or override top level keys
+		for b.stack.Size() > 1 {
+			d.popScope(b.stack)
+		}
+		if b.skip {
+			d.pushScope(b.stack, make(propertyMap))
+		}
+		return true
+	}
+
+	for b.stack.Size() > 0 {
+		prop, ok := lookupSection(b.stack.Peek(), ev.name)
+		if ok {
+			b.enter(prop, ev)
+			return true
+		} else if b.stack.Size() == 1 {
+			break
+		}
+		d.popScope(b.stack)
+	}
+
+	if strings.Contains(ev.name, ".") && b.enterDotted(ev) {
+		return true
+	}
+
+	if d.isFallbackSection(ev.section) {
+		// keys of a DEFAULT or base section without a field are only
+		// kept for fallbacks, never set at the top level
+		d.pushScope(b.stack, make(propertyMap))
+		return true
+	}
+
+	b.unknown = d.scoping&ScopeSkipUnknown != 0
+	return false
+}
+
+// Enters the section of a header found in the section on top of the
+// stack.
+func (b *structBinder) enter(prop property, ev event) {
+	d := b.d
+	if !ev.section.active {
+		d.nextEach(prop)
+	}
+	if !d.keepSection(prop, ev) {
+		b.skip = true
+		d.pushScope(b.stack, make(propertyMap)) // repeated section ignored
+		return
+	}
+
+	var m propertyMap
+	if ev.section.active {
+		m = d.continueSection(prop)
+	} else {
+		m = d.sectionMap(prop)
+	}
+	d.pushScope(b.stack, m)
+	d.trace(TraceEvent{Kind: TraceHeader})
+	d.applyFallbacks(prop, m, ev)
+}
+
+func (b *structBinder) finish() error {
+	b.d.flushEach()
+	b.d.checkCounts()
+	return b.d.savedError
+}
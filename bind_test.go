@@ -0,0 +1,106 @@
+package ini
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScoping(t *testing.T) {
+	type config struct {
+		Name  string
+		Mysql struct {
+			Host string
+			Port int
+		} `ini:"[mysql]"`
+		Tracks []struct {
+			Title   string
+			Sources []struct {
+				BitRate int
+			} `ini:"[source]"`
+		} `ini:"[track]"`
+	}
+
+	b := []byte(`
+[mysql]
+Host=localhost
+Name=db
+
+[track]
+Title=One
+[source]
+BitRate=64
+Title=Two
+
+[unknown]
+Name=top
+Port=3306
+`)
+
+	var d config
+	dec := NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Mysql.Host != "localhost" || d.Name != "top" {
+		t.Fatal("Keys set outside their section,", d)
+	} else if len(d.Tracks) != 1 || d.Tracks[0].Title != "One" || d.Tracks[0].Sources[0].BitRate != 64 {
+		t.Fatal("Nested sections incorrect,", d.Tracks)
+	} else if u := dec.Unmatched(); len(u) != 4 || u[0].lineNum != 4 || u[1].lineNum != 10 || u[3].lineNum != 14 {
+		t.Fatal("Unmatched lines incorrect,", u)
+	}
+
+	d = config{}
+	dec = NewDecoder(bytes.NewReader(b), WithScoping(ScopeOutwardKeys))
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Name != "top" || d.Tracks[0].Title != "Two" {
+		t.Fatal("Keys not matched outwards,", d)
+	} else if u := dec.Unmatched(); len(u) != 2 || u[0].lineNum != 12 || u[1].lineNum != 14 {
+		t.Fatal("Unmatched lines incorrect,", u)
+	}
+
+	d = config{}
+	dec = NewDecoder(bytes.NewReader(b), WithScoping(ScopeSkipUnknown|ScopeOutwardKeys))
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Name != "db" {
+		t.Fatal("Keys of an unknown section matched,", d.Name)
+	} else if u := dec.Unmatched(); len(u) != 3 || u[1].lineNum != 13 {
+		t.Fatal("Unmatched lines incorrect,", u)
+	}
+}
+
+func TestNextEvents(t *testing.T) {
+	dl := DefaultDialect
+	dl.Inheritance = true
+
+	d := decodeState{dialect: dl, profile: "dev"}
+	d.init([]byte("[base]\n[prod@dev : base]\nlimits[CPU]=2\nTags[]=\"a\"\n"))
+
+	var events []event
+	for {
+		ev, ok := d.next()
+		if !ok {
+			break
+		}
+		events = append(events, ev)
+	}
+
+	if len(events) != 4 {
+		t.Fatal("Events incorrect,", events)
+	}
+	if s := events[1].section; s.name != "prod" || s.profile != "dev" || !s.active || s.base != "base" || s.lineNum != 2 {
+		t.Fatal("Header section incorrect,", s)
+	}
+	if ev := events[2]; ev.name != "limits" || ev.index != "CPU" || ev.list || ev.section != events[1].section {
+		t.Fatal("Map key event incorrect,", ev)
+	}
+	if ev := events[3]; ev.name != "tags" || !ev.list || ev.raw != `"a"` || ev.lineNum != 4 {
+		t.Fatal("List key event incorrect,", ev)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Ini2go reads a sample INI file and prints Go struct definitions with
+ * the ini tags that decode it, as a starting point for a new format.
+ *
+ * Usage:
+ *
+ *	ini2go [-type Config] [-package name] [-dialect php] [file]
+ *
+ * The file is read from standard input when none is given. Repeated
+ * sections become slices of structs, repeated keys slices, and values
+ * that look like numbers or booleans int, float64 or bool fields. See
+ * ini.InferStruct for the details.
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ini "github.com/sspencer/go-ini"
+)
+
+var dialects = map[string]ini.Dialect{
+	"default": ini.DefaultDialect,
+	"php":     ini.PHPDialect,
+	"python":  ini.PythonDialect,
+	"systemd": ini.SystemdDialect,
+	"windows": ini.WindowsDialect,
+	"git":     ini.GitDialect,
+}
+
+func main() {
+	typeName := flag.String("type", "Config", "name of the struct type")
+	pkg := flag.String("package", "", "package clause to write before the type; none by default")
+	dialect := flag.String("dialect", "default", "syntax of the file: default, php, python, systemd, windows or git")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: ini2go [-type name] [-package name] [-dialect name] [file]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dl, ok := dialects[*dialect]
+	if !ok || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var r io.Reader = os.Stdin
+	if flag.NArg() == 1 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ini2go: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	src, err := ini.InferStruct(r, *typeName, ini.WithDialect(dl))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ini2go: %s\n", err)
+		os.Exit(1)
+	}
+
+	if len(*pkg) > 0 {
+		fmt.Printf("package %s\n\n", *pkg)
+	}
+	os.Stdout.Write(src)
+}
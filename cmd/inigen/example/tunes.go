@@ -0,0 +1,55 @@
+// Package example holds types with methods generated by inigen, which
+// are tested against ini.Unmarshal.
+package example
+
+//go:generate go run .. -type=TunePlayer,Device -output=tunes_ini.go
+//go:generate go run .. -type=Device -template=device.example.ini
+
+// Level is a named type of a key.
+type Level int
+
+type Song struct {
+	SongId int
+	Title  string
+	Artist string
+}
+
+type Playlist struct {
+	PlaylistId int
+	Title      string
+	SongIds    []int `ini:"Song"`
+}
+
+type TunePlayer struct {
+	Name      string
+	Songs     []Song     `ini:"[CREATE SONG]"`
+	Playlists []Playlist `ini:"[CREATE PLAYLIST]"`
+}
+
+type Auth struct {
+	User string
+	Pass string `ini:"password"`
+}
+
+type Device struct {
+	Auth
+	Name    string  `ini:"Name,required"`
+	Volume  float32 `ini:"volume" comment:"From 0 to 1" default:"0.5"`
+	Muted   bool
+	Level   Level
+	Serial  uint64
+	Notes   string
+	Ignored string `ini:"-"`
+	Tracks  []struct {
+		Id      int
+		Title   string
+		Sources []struct {
+			Id      string
+			BitRate int
+		} `ini:"[CREATE AUDIO SOURCE]"`
+	} `ini:"[CREATE TRACK]" comment:"A track, with the sources it plays"`
+	Network struct {
+		Address string
+		DNS     []string `ini:"dns" doc:"Name servers,\ntried in order"`
+	} `ini:"[network]"`
+}
@@ -0,0 +1,246 @@
+// Code generated by inigen; DO NOT EDIT.
+
+package example
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+
+	ini "github.com/sspencer/go-ini"
+)
+
+// UnmarshalINI decodes an INI file into v without reflection, the way
+// ini.Unmarshal does.
+func (v *TunePlayer) UnmarshalINI(data []byte) error {
+	s := ini.NewScanner(bytes.NewReader(data))
+	scope := []int{0}
+	for {
+		tok, err := s.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch tok.Kind {
+		case ini.TokenSectionHeader:
+			name, entered := ini.SectionName(tok), false
+			for i := len(scope) - 1; i >= 0 && !entered; i-- {
+				entered = true
+				switch {
+				case scope[i] == 0 && name == "create song":
+					ini.AppendZero(&v.Songs)
+					scope = append(scope[:i+1], 1)
+				case scope[i] == 0 && name == "create playlist":
+					ini.AppendZero(&v.Playlists)
+					scope = append(scope[:i+1], 2)
+				default:
+					entered = false
+				}
+			}
+			if !entered {
+				scope = scope[:1]
+			}
+
+		case ini.TokenKeyValue:
+			switch scope[len(scope)-1] {
+			case 0:
+				switch ini.KeyName(tok) {
+				case "name":
+					v.Name = tok.Value
+				}
+			case 1:
+				switch ini.KeyName(tok) {
+				case "songid":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					ini.Last(v.Songs).SongId = int(n)
+				case "title":
+					ini.Last(v.Songs).Title = tok.Value
+				case "artist":
+					ini.Last(v.Songs).Artist = tok.Value
+				}
+			case 2:
+				switch key, list := ini.KeyName(tok), ini.ListName(tok); {
+				case key == "playlistid":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					ini.Last(v.Playlists).PlaylistId = int(n)
+				case key == "title":
+					ini.Last(v.Playlists).Title = tok.Value
+				case list == "song":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					ini.Last(v.Playlists).SongIds = append(ini.Last(v.Playlists).SongIds, int(n))
+				}
+			}
+		}
+	}
+}
+
+// MarshalINI encodes v as an INI file without reflection, the way
+// ini.Marshal does.
+func (v *TunePlayer) MarshalINI() ([]byte, error) {
+	var w ini.Writer
+	w.Key("Name", v.Name)
+	for _, s1 := range v.Songs {
+		w.Section("CREATE SONG")
+		w.Key("SongId", strconv.FormatInt(int64(s1.SongId), 10))
+		w.Key("Title", s1.Title)
+		w.Key("Artist", s1.Artist)
+	}
+	for _, s2 := range v.Playlists {
+		w.Section("CREATE PLAYLIST")
+		w.Key("PlaylistId", strconv.FormatInt(int64(s2.PlaylistId), 10))
+		w.Key("Title", s2.Title)
+		for _, x := range s2.SongIds {
+			w.Key("Song", strconv.FormatInt(int64(x), 10))
+		}
+	}
+	if err := w.Err(); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+// UnmarshalINI decodes an INI file into v without reflection, the way
+// ini.Unmarshal does.
+func (v *Device) UnmarshalINI(data []byte) error {
+	s := ini.NewScanner(bytes.NewReader(data))
+	scope := []int{0}
+	for {
+		tok, err := s.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch tok.Kind {
+		case ini.TokenSectionHeader:
+			name, entered := ini.SectionName(tok), false
+			for i := len(scope) - 1; i >= 0 && !entered; i-- {
+				entered = true
+				switch {
+				case scope[i] == 0 && name == "create track":
+					ini.AppendZero(&v.Tracks)
+					scope = append(scope[:i+1], 1)
+				case scope[i] == 0 && name == "network":
+					scope = append(scope[:i+1], 3)
+				case scope[i] == 1 && name == "create audio source":
+					ini.AppendZero(&ini.Last(v.Tracks).Sources)
+					scope = append(scope[:i+1], 2)
+				default:
+					entered = false
+				}
+			}
+			if !entered {
+				scope = scope[:1]
+			}
+
+		case ini.TokenKeyValue:
+			switch scope[len(scope)-1] {
+			case 0:
+				switch ini.KeyName(tok) {
+				case "user":
+					v.Auth.User = tok.Value
+				case "password":
+					v.Auth.Pass = tok.Value
+				case "name":
+					v.Name = tok.Value
+				case "volume":
+					n, err := ini.ParseFloat(tok, 32)
+					if err != nil {
+						return err
+					}
+					v.Volume = float32(n)
+				case "muted":
+					v.Muted = ini.ParseBool(tok)
+				case "level":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					v.Level = Level(n)
+				case "serial":
+					n, err := ini.ParseUint(tok, 64)
+					if err != nil {
+						return err
+					}
+					v.Serial = uint64(n)
+				case "notes":
+					v.Notes = tok.Value
+				}
+			case 1:
+				switch ini.KeyName(tok) {
+				case "id":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					ini.Last(v.Tracks).Id = int(n)
+				case "title":
+					ini.Last(v.Tracks).Title = tok.Value
+				}
+			case 2:
+				switch ini.KeyName(tok) {
+				case "id":
+					ini.Last(ini.Last(v.Tracks).Sources).Id = tok.Value
+				case "bitrate":
+					n, err := ini.ParseInt(tok, 0)
+					if err != nil {
+						return err
+					}
+					ini.Last(ini.Last(v.Tracks).Sources).BitRate = int(n)
+				}
+			case 3:
+				switch key, list := ini.KeyName(tok), ini.ListName(tok); {
+				case key == "address":
+					v.Network.Address = tok.Value
+				case list == "dns":
+					v.Network.DNS = append(v.Network.DNS, tok.Value)
+				}
+			}
+		}
+	}
+}
+
+// MarshalINI encodes v as an INI file without reflection, the way
+// ini.Marshal does.
+func (v *Device) MarshalINI() ([]byte, error) {
+	var w ini.Writer
+	w.Key("User", v.Auth.User)
+	w.Key("password", v.Auth.Pass)
+	w.Key("Name", v.Name)
+	w.Key("volume", strconv.FormatFloat(float64(v.Volume), 'g', -1, 32))
+	w.Key("Muted", strconv.FormatBool(bool(v.Muted)))
+	w.Key("Level", strconv.FormatInt(int64(v.Level), 10))
+	w.Key("Serial", strconv.FormatUint(uint64(v.Serial), 10))
+	w.Key("Notes", v.Notes)
+	for _, s1 := range v.Tracks {
+		w.Section("CREATE TRACK")
+		w.Key("Id", strconv.FormatInt(int64(s1.Id), 10))
+		w.Key("Title", s1.Title)
+		for _, s2 := range s1.Sources {
+			w.Section("CREATE AUDIO SOURCE")
+			w.Key("Id", s2.Id)
+			w.Key("BitRate", strconv.FormatInt(int64(s2.BitRate), 10))
+		}
+	}
+	w.Section("network")
+	w.Key("Address", v.Network.Address)
+	for _, x := range v.Network.DNS {
+		w.Key("dns", x)
+	}
+	if err := w.Err(); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package example
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	ini "github.com/sspencer/go-ini"
+)
+
+var tunesData = []byte(`
+Name=Tunes
+
+[CREATE SONG]
+SongId=21348
+Title=Long Way to Go
+Artist=The Hedges
+
+[CREATE SONG]
+SongId=9855
+Title=It Wasn't Safe
+
+[CREATE PLAYLIST]
+PlaylistId=3
+Title=Mix
+Song=21348
+Song=9855
+`)
+
+var deviceData = []byte(`
+User=admin
+password=secret
+Name=speaker-2
+Name[]=ignored
+volume=0.75
+Muted=yes
+Level=3
+Serial=18446744073709551615
+Notes=front room, by the window
+Ignored=set
+
+[CREATE TRACK]
+Id=1
+Title=Intro
+
+[CREATE AUDIO SOURCE]
+Id=left
+BitRate=320
+
+[CREATE AUDIO SOURCE]
+Id=right
+BitRate=256
+
+[CREATE TRACK]
+Id=2
+Title=Outro
+
+[network]
+Address=10.0.0.7
+dns=10.0.0.1
+dns=10.0.0.2
+dns[]=10.0.0.3
+`)
+
+func TestUnmarshalINI(t *testing.T) {
+	var tunes, tunesReflect TunePlayer
+	if err := ini.Unmarshal(tunesData, &tunes); err != nil {
+		t.Fatal(err)
+	} else if err := ini.NewDecoder(bytes.NewReader(tunesData)).Decode(&tunesReflect); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(tunes, tunesReflect) {
+		t.Fatalf("Generated and reflection decoding differ,\n%v\n%v", tunes, tunesReflect)
+	} else if len(tunes.Songs) != 2 || tunes.Playlists[0].SongIds[1] != 9855 {
+		t.Fatal("Tunes decoded incorrectly,", tunes)
+	}
+
+	var dev, devReflect Device
+	if err := ini.Unmarshal(deviceData, &dev); err != nil {
+		t.Fatal(err)
+	} else if err := ini.NewDecoder(bytes.NewReader(deviceData)).Decode(&devReflect); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(dev, devReflect) {
+		t.Fatalf("Generated and reflection decoding differ,\n%v\n%v", dev, devReflect)
+	} else if dev.Pass != "secret" || dev.Name != "speaker-2" || dev.Notes != "front room, by the window" || dev.Ignored != "" {
+		t.Fatal("Device decoded incorrectly,", dev)
+	} else if len(dev.Tracks) != 2 || len(dev.Tracks[0].Sources) != 2 || dev.Tracks[0].Sources[1].BitRate != 256 {
+		t.Fatal("Tracks decoded incorrectly,", dev.Tracks)
+	} else if dev.Network.Address != "10.0.0.7" || len(dev.Network.DNS) != 3 {
+		t.Fatal("Network decoded incorrectly,", dev.Network)
+	}
+
+	err := ini.Unmarshal([]byte("Name=speaker\nLevel=loud\n"), &dev)
+	if err == nil || err.Error() != `Invalid int on line 2: "Level=loud"` {
+		t.Fatal("Expected invalid int error,", err)
+	}
+}
+
+func TestMarshalINI(t *testing.T) {
+	var dev Device
+	if err := ini.Unmarshal(deviceData, &dev); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ini.Marshal(&dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ini.NewEncoder(&buf).Encode(&dev); err != nil {
+		t.Fatal(err)
+	} else if string(b) != buf.String() {
+		t.Fatalf("Generated and reflection encoding differ,\n%s\n%s", b, buf.String())
+	}
+
+	var back Device
+	if err := ini.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(dev, back) {
+		t.Fatalf("Device changed in a round trip,\n%v\n%v", dev, back)
+	}
+
+	dev.Notes = "front room\nby the window"
+	if _, err := ini.Marshal(&dev); err == nil || err.Error() != "Can't encode multi-line value of Notes in this dialect" {
+		t.Fatal("Expected multi-line error,", err)
+	}
+}
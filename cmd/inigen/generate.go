@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// generatedBy starts the comment marking the files inigen writes.
+const generatedBy = "// Code generated by inigen"
+
+// Returns the source of a file with the methods of the named types of
+// the package in dir. The file named skip, an earlier output, is not
+// read.
+func generate(dir string, typeNames []string, skip string) ([]byte, error) {
+	pkg, err := loadPackage(dir, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg}
+	for _, name := range typeNames {
+		name = strings.TrimSpace(name)
+		root, err := g.root(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		g.unmarshal(name, root)
+		g.marshal(name, root)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s; DO NOT EDIT.\n\npackage %s\n\nimport (\n", generatedBy, pkg.Name())
+	fmt.Fprintf(&out, "\t\"bytes\"\n\t\"io\"\n")
+	if g.strconv {
+		fmt.Fprintf(&out, "\t\"strconv\"\n")
+	}
+	fmt.Fprintf(&out, "\n\tini \"github.com/sspencer/go-ini\"\n)\n")
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting output: %s", err)
+	}
+	return src, nil
+}
+
+// Parses and type checks the package in dir, leaving out tests and
+// files written by inigen. Errors in other declarations than the types
+// are ignored.
+func loadPackage(dir, skip string) (*types.Package, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == skip {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Comments) > 0 && strings.HasPrefix(f.Comments[0].Text(), generatedBy[3:]) {
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	return pkg, nil
+}
+
+// Returns the section of the named struct type of the package.
+func (g *generator) root(dir, name string) (*section, error) {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("type %s not found in %s", name, dir)
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", name)
+	}
+
+	g.next = 0
+	root, err := g.section(st, map[*types.Struct]bool{})
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %s", name, err)
+	}
+	return root, nil
+}
+
+// A section is a struct decoded from the keys following a header, or
+// the whole file for the struct at the top.
+type section struct {
+	id   int
+	keys []key
+	subs []sub
+}
+
+// A key is a field set by NAME=VALUE lines.
+type key struct {
+	name  string // as in the tag, or the field name
+	path  string // selector of the field, like .Auth.User
+	typ   types.Type
+	slice bool // repeated key, typ is the element type
+	doc   string
+	def   string // default tag, written by -template for the zero value
+}
+
+// A sub is a section inside a struct.
+type sub struct {
+	name  string // without brackets
+	path  string
+	slice bool // repeated section
+	sec   *section
+	doc   string
+}
+
+// A field is a struct field the way ini.Unmarshal sees it.
+type field struct {
+	name   string
+	path   string
+	index  []int
+	typ    types.Type
+	tagged bool
+	depth  int
+	doc    string // comment or doc tag
+	def    string // default tag
+}
+
+type generator struct {
+	pkg     *types.Package
+	buf     bytes.Buffer
+	next    int  // id of the next section
+	strconv bool // strconv is imported
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// Builds the section of a struct and the sections inside it.
+func (g *generator) section(st *types.Struct, open map[*types.Struct]bool) (*section, error) {
+	if open[st] {
+		return nil, fmt.Errorf("recursive struct type")
+	}
+	open[st] = true
+	defer delete(open, st)
+
+	fields, err := structFields(st)
+	if err != nil {
+		return nil, err
+	}
+
+	sec := &section{id: g.next}
+	g.next++
+
+	for _, f := range fields {
+		t := f.typ
+		slice := false
+		if s, ok := t.Underlying().(*types.Slice); ok {
+			t, slice = s.Elem(), true
+		}
+
+		if inner, ok := t.Underlying().(*types.Struct); ok {
+			child, err := g.section(inner, open)
+			if err != nil {
+				return nil, err
+			}
+			name := f.name
+			if len(name) > 1 && name[0] == '[' && name[len(name)-1] == ']' {
+				name = strings.TrimSpace(name[1 : len(name)-1])
+			}
+			sec.subs = append(sec.subs, sub{name, f.path, slice, child, f.doc})
+			continue
+		}
+
+		if err := g.checkScalar(t); err != nil {
+			return nil, fmt.Errorf("field %s: %s", f.path[1:], err)
+		}
+		sec.keys = append(sec.keys, key{f.name, f.path, t, slice, f.doc, f.def})
+	}
+	return sec, nil
+}
+
+// Reports an error for a type that isn't a string, bool or number of
+// this package or a predeclared one.
+func (g *generator) checkScalar(t types.Type) error {
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != g.pkg {
+		return fmt.Errorf("type %s is not supported", t)
+	}
+	if b, ok := t.Underlying().(*types.Basic); ok && b.Info()&(types.IsString|types.IsBoolean|types.IsInteger|types.IsFloat) != 0 {
+		return nil
+	}
+	return fmt.Errorf("type %s is not supported", t)
+}
+
+/*
+ * Returns the fields of a struct the way ini.Unmarshal finds them:
+ * the fields of embedded structs without a tag name, or with the
+ * "inline" option, are promoted, and of several fields of the same name
+ * the least deeply embedded one wins, then a tagged one.
+ */
+func structFields(st *types.Struct) ([]field, error) {
+	type embedded struct {
+		st    *types.Struct
+		path  string
+		index []int
+	}
+
+	var fields []field
+	visited := make(map[*types.Struct]bool)
+	next := []embedded{{st: st}}
+
+	for depth := 0; len(next) > 0; depth++ {
+		current := next
+		next = nil
+
+		for _, e := range current {
+			if visited[e.st] {
+				continue
+			}
+
+			for i := 0; i < e.st.NumFields(); i++ {
+				v := e.st.Field(i)
+				tag := reflect.StructTag(e.st.Tag(i))
+				name, opts := parseTag(tag.Get("ini"))
+				if name == "-" {
+					continue
+				}
+
+				inline := false
+				for _, opt := range opts {
+					if opt == "inline" {
+						inline = true
+					} else if len(opt) > 0 && opt != "required" {
+						return nil, fmt.Errorf("field %s: option %q is not supported", v.Name(), opt)
+					}
+				}
+
+				index := append(append([]int(nil), e.index...), i)
+				path := e.path + "." + v.Name()
+
+				ft := v.Type()
+				_, isPtr := ft.Underlying().(*types.Pointer)
+				if isPtr {
+					ft = ft.Underlying().(*types.Pointer).Elem()
+				}
+				inner, isStruct := ft.Underlying().(*types.Struct)
+
+				if isStruct && ((v.Embedded() && len(name) == 0) || inline) {
+					if isPtr {
+						return nil, fmt.Errorf("field %s: embedded pointers are not supported", path[1:])
+					}
+					next = append(next, embedded{inner, path, index})
+					continue
+				} else if !v.Exported() {
+					continue
+				} else if isPtr {
+					return nil, fmt.Errorf("field %s: pointers are not supported", path[1:])
+				}
+
+				tagged := len(name) > 0
+				if !tagged {
+					name = v.Name()
+				}
+				doc := tag.Get("comment")
+				if len(doc) == 0 {
+					doc = tag.Get("doc")
+				}
+				def := tag.Get("default")
+				fields = append(fields, field{name, path, index, v.Type(), tagged, depth, doc, def})
+			}
+		}
+
+		for _, e := range current {
+			visited[e.st] = true
+		}
+	}
+
+	byName := make(map[string][]field)
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	var out []field
+	for _, f := range fields {
+		if dominant, ok := dominantField(byName[f.name]); ok && dominant.path == f.path {
+			out = append(out, f)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].index, out[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return out, nil
+}
+
+func dominantField(fields []field) (field, bool) {
+	var best []field
+	for _, f := range fields {
+		if len(best) == 0 || f.depth < best[0].depth {
+			best = []field{f}
+		} else if f.depth == best[0].depth {
+			best = append(best, f)
+		}
+	}
+
+	if len(best) == 1 {
+		return best[0], true
+	}
+
+	var tagged []field
+	for _, f := range best {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// Splits an ini tag into its trimmed name and options.
+func parseTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return strings.TrimSpace(parts[0]), parts[1:]
+}
+
+// Returns the type of a value as written in the package.
+func (g *generator) typeName(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		return p.Name()
+	})
+}
+
+// Matches tags the way DefaultDialect does, ignoring case.
+func fold(s string) string {
+	return strings.ToLower(s)
+}
+
+/*
+ * Writes the UnmarshalINI method. The ids of the sections entered are
+ * kept in scope, innermost last. A header is looked up in the innermost
+ * section, then outwards, the way Unmarshal does it, and a key is only
+ * looked up in the innermost section.
+ */
+func (g *generator) unmarshal(name string, root *section) {
+	g.printf("\n// UnmarshalINI decodes an INI file into v without reflection, the way\n")
+	g.printf("// ini.Unmarshal does.\n")
+	g.printf("func (v *%s) UnmarshalINI(data []byte) error {\n", name)
+	g.printf("s := ini.NewScanner(bytes.NewReader(data))\n")
+	g.printf("scope := []int{0}\n")
+	g.printf("for {\n")
+	g.printf("tok, err := s.Next()\n")
+	g.printf("if err == io.EOF {\nreturn nil\n} else if err != nil {\nreturn err\n}\n\n")
+	g.printf("switch tok.Kind {\n")
+
+	g.printf("case ini.TokenSectionHeader:\n")
+	if len(root.subs) == 0 {
+		g.printf("scope = scope[:1]\n\n")
+	} else {
+		g.headers(root)
+	}
+
+	g.printf("case ini.TokenKeyValue:\n")
+	g.printf("switch scope[len(scope)-1] {\n")
+	g.keyCases(root, "v")
+	g.printf("}\n")
+
+	g.printf("}\n}\n}\n")
+}
+
+// Writes the statements entering the section of a header.
+func (g *generator) headers(root *section) {
+	g.printf("name, entered := ini.SectionName(tok), false\n")
+	g.printf("for i := len(scope) - 1; i >= 0 && !entered; i-- {\n")
+	g.printf("entered = true\n")
+	g.printf("switch {\n")
+	g.headerCases(root, "v")
+	g.printf("default:\nentered = false\n}\n}\n")
+	g.printf("if !entered {\nscope = scope[:1]\n}\n\n")
+}
+
+// Writes the cases entering the sections inside sec, and inside those.
+func (g *generator) headerCases(sec *section, expr string) {
+	for _, s := range sec.subs {
+		g.printf("case scope[i] == %d && name == %q:\n", sec.id, fold(s.name))
+		if s.slice {
+			g.printf("ini.AppendZero(&%s%s)\n", expr, s.path)
+		}
+		g.printf("scope = append(scope[:i+1], %d)\n", s.sec.id)
+	}
+	for _, s := range sec.subs {
+		g.headerCases(s.sec, subExpr(expr, s))
+	}
+}
+
+// Returns the expression of the section being decoded of s, in the
+// section expr.
+func subExpr(expr string, s sub) string {
+	if s.slice {
+		return "ini.Last(" + expr + s.path + ")"
+	}
+	return expr + s.path
+}
+
+// Writes the cases setting the keys of sec, and of the sections inside.
+func (g *generator) keyCases(sec *section, expr string) {
+	g.printf("case %d:\n", sec.id)
+	if len(sec.keys) > 0 {
+		// a key like Song[] only sets a slice, other keys are matched
+		// as written
+		var slices, scalars bool
+		for _, k := range sec.keys {
+			slices, scalars = slices || k.slice, scalars || !k.slice
+		}
+
+		switch {
+		case slices && scalars:
+			g.printf("switch key, list := ini.KeyName(tok), ini.ListName(tok); {\n")
+		case slices:
+			g.printf("switch ini.ListName(tok) {\n")
+		default:
+			g.printf("switch ini.KeyName(tok) {\n")
+		}
+		for _, k := range sec.keys {
+			switch {
+			case slices && scalars && k.slice:
+				g.printf("case list == %q:\n", fold(k.name))
+			case slices && scalars:
+				g.printf("case key == %q:\n", fold(k.name))
+			default:
+				g.printf("case %q:\n", fold(k.name))
+			}
+			g.setKey(expr+k.path, k)
+		}
+		g.printf("}\n")
+	}
+	for _, s := range sec.subs {
+		g.keyCases(s.sec, subExpr(expr, s))
+	}
+}
+
+// Writes the statements setting a field to the value of tok.
+func (g *generator) setKey(dst string, k key) {
+	b := k.typ.Underlying().(*types.Basic)
+	conv := g.typeName(k.typ)
+
+	var value string
+	switch {
+	case b.Info()&types.IsString != 0:
+		value = "tok.Value"
+	case b.Info()&types.IsBoolean != 0:
+		value = "ini.ParseBool(tok)"
+	default:
+		parse := "ParseInt"
+		if b.Info()&types.IsUnsigned != 0 {
+			parse = "ParseUint"
+		} else if b.Info()&types.IsFloat != 0 {
+			parse = "ParseFloat"
+		}
+		g.printf("n, err := ini.%s(tok, %d)\nif err != nil {\nreturn err\n}\n", parse, bitSize(b))
+		value = "n"
+	}
+
+	if conv != b.Name() || value == "n" {
+		value = conv + "(" + value + ")"
+	}
+	if k.slice {
+		g.printf("%s = append(%s, %s)\n", dst, dst, value)
+	} else {
+		g.printf("%s = %s\n", dst, value)
+	}
+}
+
+// Returns the bit size to parse a number with, zero for int and uint.
+func bitSize(b *types.Basic) int {
+	switch b.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32, types.Float32:
+		return 32
+	case types.Int64, types.Uint64, types.Float64:
+		return 64
+	}
+	return 0
+}
+
+/*
+ * Writes the MarshalINI method: the keys of a struct, then each of its
+ * sections followed by the sections inside it, the way ini.Marshal
+ * writes them.
+ */
+func (g *generator) marshal(name string, root *section) {
+	g.printf("\n// MarshalINI encodes v as an INI file without reflection, the way\n")
+	g.printf("// ini.Marshal does.\n")
+	g.printf("func (v *%s) MarshalINI() ([]byte, error) {\n", name)
+	g.printf("var w ini.Writer\n")
+	g.writeSection(root, "v")
+	g.printf("if err := w.Err(); err != nil {\nreturn nil, err\n}\n")
+	g.printf("return w.Bytes(), nil\n}\n")
+}
+
+func (g *generator) writeSection(sec *section, expr string) {
+	for _, k := range sec.keys {
+		if k.slice {
+			g.printf("for _, x := range %s%s {\n", expr, k.path)
+			g.printf("w.Key(%q, %s)\n", k.name, g.format("x", k.typ))
+			g.printf("}\n")
+		} else {
+			g.printf("w.Key(%q, %s)\n", k.name, g.format(expr+k.path, k.typ))
+		}
+	}
+
+	for _, s := range sec.subs {
+		if s.slice {
+			elem := fmt.Sprintf("s%d", s.sec.id)
+			g.printf("for _, %s := range %s%s {\n", elem, expr, s.path)
+			g.printf("w.Section(%q)\n", s.name)
+			g.writeSection(s.sec, elem)
+			g.printf("}\n")
+		} else {
+			g.printf("w.Section(%q)\n", s.name)
+			g.writeSection(s.sec, expr+s.path)
+		}
+	}
+}
+
+// Returns the expression of the text of a value.
+func (g *generator) format(expr string, t types.Type) string {
+	b := t.Underlying().(*types.Basic)
+	switch {
+	case b.Info()&types.IsString != 0:
+		if g.typeName(t) != "string" {
+			return "string(" + expr + ")"
+		}
+		return expr
+	case b.Info()&types.IsBoolean != 0:
+		g.strconv = true
+		return "strconv.FormatBool(bool(" + expr + "))"
+	case b.Info()&types.IsUnsigned != 0:
+		g.strconv = true
+		return "strconv.FormatUint(uint64(" + expr + "), 10)"
+	case b.Info()&types.IsFloat != 0:
+		g.strconv = true
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, %d)", expr, bitSize(b))
+	}
+	g.strconv = true
+	return "strconv.FormatInt(int64(" + expr + "), 10)"
+}
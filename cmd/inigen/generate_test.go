@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	ini "github.com/sspencer/go-ini"
+	"github.com/sspencer/go-ini/cmd/inigen/example"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("example", []string{"TunePlayer", "Device"}, "tunes_ini.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("example", "tunes_ini.go"))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(src) != string(want) {
+		t.Fatal("Generated code differs from example/tunes_ini.go, run go generate in example")
+	}
+
+	_, err = generate("example", []string{"Missing"}, "")
+	if err == nil || err.Error() != "type Missing not found in example" {
+		t.Fatal("Expected missing type error,", err)
+	}
+
+	_, err = generate(filepath.Join("testdata", "bad"), []string{"Config"}, "")
+	if err == nil || err.Error() != "type Config: field Limits: type map[string]int is not supported" {
+		t.Fatal("Expected unsupported type error,", err)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	src, err := template("example", "Device", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("example", "device.example.ini"))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(src) != string(want) {
+		t.Fatal("Template differs from example/device.example.ini, run go generate in example")
+	}
+
+	b, err := ini.Template(&example.Device{})
+	if err != nil {
+		t.Fatal(err)
+	} else if string(src) != string(b) {
+		t.Fatalf("Template differs from ini.Template,\n%s\n%s", src, b)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Inigen generates UnmarshalINI and MarshalINI methods for struct types
+ * tagged for package ini, which decode and encode them without
+ * reflection. ini.Unmarshal and ini.Marshal call the methods when a type
+ * has them, as do ini.Decode, ini.DecodeFile and ini.MustLoad without
+ * options.
+ *
+ * Usage, in a file of the package declaring the types:
+ *
+ *	//go:generate inigen -type=Config,Playlist
+ *
+ * The methods read DefaultDialect and follow the same tags as
+ * Unmarshal: `ini:"[SECTION]"` sections, "-" to leave a field out,
+ * embedded structs and the "inline" option, slices of scalars as
+ * repeated keys and slices of structs as repeated sections. The
+ * "required" option, read by ini.JSONSchema, is allowed. Fields the
+ * methods can't handle without reflection, like maps, arrays, pointers
+ * and tag options such as split or dotted, are reported as errors; such
+ * types are left to Unmarshal.
+ *
+ * With -template, inigen writes a sample INI file of a single type
+ * instead, the way ini.Template writes its zero value: every key and
+ * section, keys set to their default tags, comments from the comment or
+ * doc tags of the fields, and one block of each repeated section,
+ * marked as repeatable.
+ *
+ *	//go:generate inigen -type=Config -template=config.example.ini
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names; required")
+	output := flag.String("output", "", "output file name; default <type>_ini.go")
+	tmpl := flag.String("template", "", "write a sample INI file of the type to this file instead of methods")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: inigen -type=T[,T...] [-output file] [directory]\n")
+		fmt.Fprintf(os.Stderr, "       inigen -type=T -template=file [directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if len(*typeNames) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	types := strings.Split(*typeNames, ",")
+	name := *output
+	if len(*tmpl) > 0 {
+		name = *tmpl
+	} else if len(name) == 0 {
+		name = strings.ToLower(types[0]) + "_ini.go"
+	}
+	name = filepath.Join(dir, name)
+
+	var src []byte
+	var err error
+	if len(*tmpl) > 0 {
+		if len(types) > 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		src, err = template(dir, types[0], "")
+	} else {
+		src, err = generate(dir, types, filepath.Base(name))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inigen: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "inigen: %s\n", err)
+		os.Exit(1)
+	}
+}
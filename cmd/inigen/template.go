@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"strings"
+)
+
+// Returns a sample INI file of the named type of the package in dir,
+// the way ini.Template writes it for a zero value.
+func template(dir, typeName, skip string) ([]byte, error) {
+	pkg, err := loadPackage(dir, skip)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg}
+	root, err := g.root(dir, strings.TrimSpace(typeName))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := writeTemplate(&out, root); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Writes the keys of a section with their default tags or zero values,
+// then each of its sections once, after their comment and the mark of a
+// repeated one.
+func writeTemplate(out *bytes.Buffer, sec *section) error {
+	for _, k := range sec.keys {
+		value := zeroText(k.typ)
+		if len(k.def) > 0 {
+			if k.slice {
+				return fmt.Errorf("field %s: invalid default tag %q", k.path[1:], k.def)
+			}
+			value = k.def
+		}
+		writeComment(out, k.doc)
+		fmt.Fprintf(out, "%s=%s\n", k.name, value)
+	}
+
+	for _, s := range sec.subs {
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		writeComment(out, s.doc)
+		if s.slice {
+			writeComment(out, "["+s.name+"] may be repeated")
+		}
+		fmt.Fprintf(out, "[%s]\n", s.name)
+		if err := writeTemplate(out, s.sec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeComment(out *bytes.Buffer, s string) {
+	if len(s) == 0 {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		fmt.Fprintf(out, "; %s\n", line)
+	}
+}
+
+// Returns the text of the zero value of a scalar type.
+func zeroText(t types.Type) string {
+	b := t.Underlying().(*types.Basic)
+	switch {
+	case b.Info()&types.IsString != 0:
+		return ""
+	case b.Info()&types.IsBoolean != 0:
+		return "false"
+	}
+	return "0"
+}
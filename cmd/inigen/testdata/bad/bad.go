@@ -0,0 +1,6 @@
+package bad
+
+type Config struct {
+	Name   string
+	Limits map[string]int
+}
@@ -6,7 +6,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"log/slog"
 	"reflect"
 	"strconv"
 	"strings"
@@ -30,6 +30,46 @@ type decodeState struct {
 	scanner    *bufio.Scanner
 	savedError error
 	unmatched  []Unmatched
+	dotted     bool // resolve every dotted header as a path of sections
+	scoping    Scoping
+
+	dialect   Dialect
+	physLine  int      // physical lines read, lineNum is where line starts
+	peeked    physical // physical line read ahead of the current line
+	hasPeeked bool
+	lines     []physical // physical lines of the current line
+	offset    int64      // bytes read by the scanner
+	advance   int        // bytes of the last physical line, with its newline
+	heredoc   bool       // line is "KEY <<EOT", key and value hold the result
+	key       string
+	value     string
+
+	section   sectionHeader       // header of the section being read, see next
+	recorded  map[string][]event  // values kept for fallbacks, by section
+	bases     map[int]sectionBase // base section by header line
+	baseOf    map[string]sectionBase
+	inherited map[fieldKey]bool // slices filled by fallbacks, see setProperty
+	filled    map[fieldKey]bool // struct sections fallbacks were applied to
+
+	profile    string            // sections [NAME@profile] override [NAME]
+	overridden map[fieldKey]bool // fields set by the active profile
+
+	duplicates  DuplicatePolicy
+	onDuplicate func(Duplicate)
+	seen        map[fieldKey]int // line that first set a field or section
+	inFallback  bool             // values are set by applyFallbacks
+	inferTypes  bool             // see Decoder.InferTypes
+
+	strict    bool // unmatched lines are errors
+	exactKeys bool // see WithCaseSensitiveKeys, applied over the dialect
+	maxLine   int  // longest physical line in bytes, or zero for the default
+	logger    *slog.Logger
+	tracer    Tracer
+	tagName   string // struct tag key, "ini" unless set
+	valueHook ValueHook
+
+	counts map[fieldKey]arrayCount // values set in fixed size arrays
+	each   *eachSection            // see DecodeEach
 }
 
 type property struct {
@@ -39,64 +79,45 @@ type property struct {
 	isArray  bool
 	//array         []interface{}
 	isInitialized bool
+	isSection     bool   // struct or slice of structs, entered by a header
+	dotted        bool   // header paths like [server.tls] resolve into this section
+	isMap         bool   // map[string]T set by keys like limits[cpu]
+	split         string // separator of a list on one line, see splitList
+	quoted        bool   // elements of a split list may be quoted
+	duplicates    DuplicatePolicy
+	exact         bool // arrays must get as many values as they hold
 }
 
 type propertyMap map[string]property
 
-//------------------------------------------------------------------
-
-// NewStack returns a new stack.
-func NewPropMapStack() *PropMapStack {
-	return &PropMapStack{}
-}
-
-// Stack is a basic LIFO stack that resizes as needed.
-type PropMapStack struct {
-	items []propertyMap
-	count int
-}
-
-// Push adds an iterm to the top of the stack
-func (s *PropMapStack) Push(item propertyMap) {
-	s.items = append(s.items[:s.count], item)
-	s.count++
+func (m propertyMap) lookup(name string) (property, bool) {
+	prop, ok := m[name]
+	return prop, ok
 }
 
-// Pop removes the top item (LIFO) from the stack
-func (s *PropMapStack) Pop() propertyMap {
-	if s.count == 0 {
-		return nil
-	}
-
-	s.count--
-	return s.items[s.count]
-}
-
-// Peek returns item at top of stack without removing it
-func (s *PropMapStack) Peek() propertyMap {
-	if s.count == 0 {
-		return nil
-	}
-
-	return s.items[s.count-1]
-}
+//------------------------------------------------------------------
 
-// Empty returns true when stack is empty, false otherwise
-func (s *PropMapStack) Empty() bool {
-	return s.count == 0
-}
+// PropMapStack holds the property maps of the sections being decoded,
+// innermost on top.
+type PropMapStack = Stack[propertyMap]
 
-// Size returns the number of items in the stack
-func (s *PropMapStack) Size() int {
-	return s.count
+// NewPropMapStack returns a new stack of property maps.
+func NewPropMapStack() *PropMapStack {
+	return &PropMapStack{}
 }
 
 /*
  * Unmarshal parses the INI-encoded data and stores the result
- * in the value pointed to by v.
+ * in the value pointed to by v. If v implements Unmarshaler, its
+ * UnmarshalINI method decodes the data instead.
  */
 func Unmarshal(data []byte, v interface{}) error {
+	if u, ok := v.(Unmarshaler); ok {
+		return u.UnmarshalINI(data)
+	}
+
 	var d decodeState
+	d.dialect = DefaultDialect
 	d.init(data)
 	return d.unmarshal(v)
 }
@@ -130,13 +151,249 @@ func (p property) String() string {
  * Convenience function to prep for decoding byte array.
  */
 func (d *decodeState) init(data []byte) *decodeState {
+	d.reset()
+	d.scanner = d.newScanner(bytes.NewReader(data))
+
+	if d.needsPrescan() {
+		d.prescan(data)
+	}
+
+	return d
+}
 
+/*
+ * Preps for decoding straight from a reader, a line at a time. Only a
+ * dialect with DEFAULT sections or inheritance needs the whole file
+ * ahead of decoding, which is then read into memory.
+ */
+func (d *decodeState) initReader(r io.Reader) error {
+	if d.exactKeys {
+		d.dialect.CaseSensitive = true
+	}
+
+	if d.needsPrescan() {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		d.init(data)
+		return nil
+	}
+
+	d.reset()
+	d.scanner = d.newScanner(r)
+	return nil
+}
+
+func (d *decodeState) needsPrescan() bool {
+	return len(d.dialect.DefaultSection) > 0 || d.dialect.Inheritance
+}
+
+func (d *decodeState) reset() {
 	d.lineNum = 0
 	d.line = ""
-	d.scanner = bufio.NewScanner(bytes.NewReader(data))
 	d.savedError = nil
+	d.physLine = 0
+	d.hasPeeked = false
+	d.lines = d.lines[:0]
+	d.offset = 0
+	d.section = sectionHeader{}
+	d.recorded = nil
+	d.bases = nil
+	d.baseOf = nil
+	d.inherited = nil
+	d.filled = nil
+	d.overridden = nil
+	d.seen = nil
+	d.counts = nil
+}
 
-	return d
+/*
+ * Reads the next logical line into d.line, joining continued lines.
+ * d.lineNum is set to the physical line the logical line starts on.
+ */
+func (d *decodeState) scan() bool {
+	d.lines = d.lines[:0]
+	line, ok := d.readPhysical()
+	if !ok {
+		return false
+	}
+
+	d.lineNum = d.physLine
+	d.heredoc = false
+
+	if d.dialect.Continuation&ContinueBackslash != 0 {
+		for strings.HasSuffix(line, "\\") {
+			line = line[:len(line)-1]
+			next, ok := d.readPhysical()
+			if !ok {
+				break
+			}
+			line += next
+		}
+	}
+
+	d.line = line
+	trimmed := strings.TrimSpace(line)
+	kind, _, _ := d.dialect.classify(trimmed)
+	if kind == lineBlank || kind == lineComment {
+		return true
+	}
+
+	if d.dialect.Continuation&ContinueHeredoc != 0 {
+		if key, tag, ok := d.heredocStart(trimmed); ok {
+			d.readHeredoc(key, tag)
+			return true
+		}
+	}
+
+	if d.dialect.Continuation&ContinueIndent != 0 && kind == lineKeyValue {
+		for {
+			next, ok := d.readPhysical()
+			if !ok {
+				break
+			}
+			if !isIndented(next) || d.dialect.isComment(strings.TrimSpace(next)) {
+				d.unread()
+				break
+			}
+			d.line += "\n" + strings.TrimSpace(next)
+		}
+	}
+
+	return true
+}
+
+func (d *decodeState) readPhysical() (string, bool) {
+	if d.hasPeeked {
+		d.hasPeeked = false
+		d.physLine++
+		d.lines = append(d.lines, d.peeked)
+		return d.peeked.text, true
+	}
+
+	if !d.scanner.Scan() {
+		if err := d.scanner.Err(); err == bufio.ErrTooLong {
+			d.saveError(&IniError{d.physLine + 1, "", fmt.Sprintf("Line longer than %d bytes", d.maxLineLength())})
+		} else if err != nil {
+			d.saveError(&readError{&IniError{d.physLine + 1, "", err.Error()}, err})
+		}
+		return "", false
+	}
+
+	p := physical{d.scanner.Text(), d.offset, d.offset + int64(len(d.scanner.Bytes()))}
+	d.offset += int64(d.advance)
+	d.physLine++
+	d.lines = append(d.lines, p)
+	return p.text, true
+}
+
+// A readError is an error of the reader, on the line being read. It
+// is an *IniError and wraps the error of the reader.
+type readError struct {
+	*IniError
+	err error
+}
+
+func (e *readError) Unwrap() []error {
+	return []error{e.IniError, e.err}
+}
+
+// Returns a scanner of physical lines that allows lines up to the
+// maximum line length.
+func (d *decodeState) newScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	n := d.maxLineLength() + 1 // and the newline
+	s.Buffer(make([]byte, 0, min(n, 4096)), n)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		d.advance = advance
+		return advance, token, err
+	})
+	return s
+}
+
+func (d *decodeState) maxLineLength() int {
+	if d.maxLine > 0 {
+		return d.maxLine
+	}
+	return bufio.MaxScanTokenSize - 1
+}
+
+// Puts back the last physical line read, to be read again.
+func (d *decodeState) unread() {
+	d.peeked = d.lines[len(d.lines)-1]
+	d.lines = d.lines[:len(d.lines)-1]
+	d.hasPeeked = true
+	d.physLine--
+}
+
+/*
+ * Reads the lines of a heredoc value up to the terminating tag. An
+ * unterminated heredoc is reported on the line that started it.
+ */
+func (d *decodeState) readHeredoc(key, tag string) {
+	var lines []string
+	for {
+		next, ok := d.readPhysical()
+		if !ok {
+			d.saveError(&IniError{d.lineNum, d.line, "Unterminated heredoc value"})
+			break
+		}
+		if strings.TrimSpace(next) == tag {
+			break
+		}
+		lines = append(lines, next)
+	}
+
+	d.heredoc = true
+	d.key = key
+	d.value = strings.Join(lines, "\n")
+}
+
+// Splits "KEY <<EOT" or "KEY=<<EOT" into the key and the tag EOT.
+func (d *decodeState) heredocStart(line string) (key, tag string, ok bool) {
+	i := strings.Index(line, "<<")
+	if i < 0 {
+		return "", "", false
+	}
+
+	tag = strings.TrimSpace(line[i+2:])
+	if !isIdentifier(tag) {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(line[:i])
+	if name, value, ok := d.dialect.split(key); ok {
+		if len(value) > 0 {
+			return "", "", false
+		}
+		key = name
+	}
+	if len(key) == 0 {
+		return "", "", false
+	}
+
+	return key, tag, true
+}
+
+func isIdentifier(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && '0' <= c && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isIndented(line string) bool {
+	return len(strings.TrimSpace(line)) > 0 && (line[0] == ' ' || line[0] == '\t')
 }
 
 /*
@@ -158,244 +415,372 @@ func (d *decodeState) generateMap(m propertyMap, v reflect.Value) {
 	if v.Type().Kind() == reflect.Ptr {
 		d.generateMap(m, v.Elem())
 	} else if v.Kind() == reflect.Struct {
-		typ := v.Type()
-		for i := 0; i < typ.NumField(); i++ {
-
-			sf := typ.Field(i)
-			f := v.Field(i)
-			kind := f.Type().Kind()
+		for _, p := range d.plan(v.Type()) {
 
-			tag := sf.Tag.Get("ini")
-			if len(tag) == 0 {
-				tag = sf.Name
+			f, ok := fieldByIndex(v, p.index, true)
+			if !ok {
+				continue
+			}
+			if p.err != nil {
+				d.saveError(p.err)
 			}
-			tag = strings.TrimSpace(strings.ToLower(tag))
-
-			st := property{tag, f, make(propertyMap), kind == reflect.Slice, true}
 
-			// some structures are just for organizing data
-			if tag != "-" {
-				m[tag] = st
+			var children propertyMap
+			if p.isStruct {
+				children = make(propertyMap)
 			}
 
-			if kind == reflect.Struct {
-				if tag == "-" {
-					d.generateMap(m, f)
-				} else {
-					// little namespacing here so property names can
-					// be the same under different sections
-					//fmt.Printf("Struct tag: %s, type: %s\n", tag, f.Type())
-					d.generateMap(st.children, f)
-				}
-			} else if kind == reflect.Slice {
-				d.generateMap(st.children, reflect.New(f.Type().Elem()))
+			m[p.tag] = property{p.tag, f, children, p.isArray, true,
+				p.isSection, p.dotted, p.isMap, p.split, p.quoted,
+				p.duplicates, p.exact}
+
+			if p.isStruct {
+				// little namespacing here so property names can
+				// be the same under different sections
+				d.generateMap(children, f)
 			}
+			// children of a slice of structs are generated for each
+			// element as its section header is read, see sectionMap
 		}
 	}
 }
 
 /*
- * Iterates line-by-line through INI file setting values into a struct.
+ * Find the section property for a header name. Brackets are optional
+ * on both sides, so "[Mysql]" finds an untagged Mysql field and "Mysql"
+ * finds a field tagged "[MYSQL]".
  */
-func (d *decodeState) unmarshal(x interface{}) error {
+func lookupSection(m propertyMap, name string) (property, bool) {
+	if prop, ok := m[name]; ok && prop.isSection {
+		return prop, true
+	}
 
-	var topMap propertyMap
-	topMap = make(propertyMap)
+	alt := "[" + name + "]"
+	if isBracketed(name) {
+		alt = name[1 : len(name)-1]
+	}
+	prop, ok := m[alt]
+	return prop, ok && prop.isSection
+}
 
-	d.generateMap(topMap, reflect.ValueOf(x))
+func isBracketed(s string) bool {
+	return len(s) > 1 && s[0] == '[' && s[len(s)-1] == ']'
+}
 
-	propStack := NewPropMapStack()
-	propStack.Push(topMap)
+/*
+ * Returns the property map used for the keys of a section that was just
+ * entered. A slice of structs gets a new element for every header.
+ */
+func (d *decodeState) sectionMap(prop property) propertyMap {
+	if !prop.isArray {
+		return prop.children
+	}
 
-	// for every line in file
-	for d.scanner.Scan() {
+	appendValue(prop.value, reflect.New(prop.value.Type().Elem()))
+	d.forgetFields(prop.value.Index(prop.value.Len() - 1))
+	return d.elementMap(prop, prop.value.Len()-1)
+}
 
-		if d.savedError != nil {
-			break // breaks on first error
-		}
+// Returns the property map of a section without starting a new element
+// of a slice of structs, unless it has none.
+func (d *decodeState) continueSection(prop property) propertyMap {
+	if prop.isArray && prop.value.Len() > 0 {
+		return d.elementMap(prop, prop.value.Len()-1)
+	}
+	return d.sectionMap(prop)
+}
 
-		d.line = d.scanner.Text()
-		d.lineNum++
+// elementMap returns the property map for element i of a slice of structs.
+func (d *decodeState) elementMap(prop property, i int) propertyMap {
+	m := make(propertyMap)
+	d.generateMap(m, prop.value.Index(i))
+	return m
+}
 
-		fmt.Printf("%03d: %s\n", d.lineNum, d.line)
+/*
+ * Resolve a dotted header like [server.tls.client] from the top level
+ * map, one path element per nested section. Every section along the
+ * path except the last keeps its current value; a slice of structs
+ * continues its last element. On success the stack is replaced by the
+ * resolved path.
+ */
+func (b *structBinder) enterDotted(ev event) bool {
+	d := b.d
+	name := ev.name
+	if isBracketed(name) {
+		name = name[1 : len(name)-1]
+	}
 
-		line := strings.TrimSpace(d.line)
+	path := strings.Split(name, ".")
+	if len(path) < 2 {
+		return false
+	}
 
-		if len(line) < 1 || line[0] == ';' || line[0] == '#' {
-			continue // skip comments
+	maps := []propertyMap{b.top}
+	m := b.top
+	var prop property
+	for i, seg := range path {
+		var ok bool
+		prop, ok = lookupSection(m, strings.TrimSpace(seg))
+		if !ok || (i == 0 && !d.dotted && !prop.dotted && !d.dialect.Subsections) {
+			return false
 		}
 
-		// Two types of lines:
-		//   1. NAME=VALUE   (at least one equal sign - breaks on first)
-		//   2. [HEADER]     (no equals sign, square brackets NOT required)
-		matches := strings.SplitN(line, "=", 2)
-		matched := false
-		pn := ""
-		pv := ""
-
-		if len(matches) == 2 {
-			// NAME=VALUE
-			pn = strings.ToLower(strings.TrimSpace(matches[0]))
-			pv = strings.TrimSpace(matches[1])
-			prop := propStack.Peek()[pn]
-
-			if prop.isInitialized {
-				if prop.isArray {
-					fmt.Println("  IS ARRAY")
-					value := reflect.New(prop.value.Type().Elem())
-					d.setValue(reflect.Indirect(value), pv)
-					appendValue(prop.value, value)
-
-				} else {
-					fmt.Println("  IS SCALAR")
-					d.setValue(prop.value, pv)
-				}
-
-				matched = true
-			}
-
-			// What if property is umatched - keep popping the stack
-			// until a potential map is found or stay within current section?
-			// Think answer is pop.
-			// NOPE
-			// Section could have unmatched name=value if user doesn't
-			// care about certain values - only stack crawling happens
-			// during numMatches==1 time?
-			// This means if there is > 1 section, there needs to be
-			// section breaks for everything
-
+		if i == len(path)-1 && !ev.section.active {
+			m = d.sectionMap(prop)
 		} else {
-			// [Header] section
-			pn = strings.ToLower(strings.TrimSpace(matches[0]))
-
-			for propStack.Size() > 0 {
-				prop := propStack.Peek()[pn]
-				if prop.isInitialized {
-					fmt.Println("  | IS INIT")
-					propStack.Push(prop.children)
-					matched = true
-					break
-				} else if propStack.Size() > 1 {
-					fmt.Println("  | IS POP")
-					_ = propStack.Pop()
-				} else {
-					fmt.Println("  | IS BREAK")
-					break
-				}
-			}
+			m = d.continueSection(prop)
 		}
+		maps = append(maps, m)
+	}
 
-		if !matched {
-			d.unmatched = append(d.unmatched, Unmatched{d.lineNum, d.line})
-		}
+	keep := d.keepSection(prop, ev)
+	if !keep {
+		b.skip = true
+		maps[len(maps)-1] = make(propertyMap) // repeated section ignored
 	}
 
-	fmt.Println("Unmatched:", d.unmatched)
+	for b.stack.Size() > 0 {
+		d.popScope(b.stack)
+	}
+	for _, m := range maps {
+		d.pushScope(b.stack, m)
+	}
+	if keep {
+		d.trace(TraceEvent{Kind: TraceHeader})
+		d.applyFallbacks(prop, m, ev)
+	}
 
-	return d.savedError
+	return true
 }
 
-func (d *decodeState) unmarshal2(x interface{}) error {
-
-	var sectionMap propertyMap = make(propertyMap)
-	var tempMap propertyMap = make(propertyMap)
+/*
+ * Reads the next logical line and classifies it. There are three types
+ * of lines:
+ *   1. NAME=VALUE   (breaks on the first delimiter of the dialect)
+ *   2. [HEADER]     (square brackets NOT required by default)
+ *   3. NAME         (a header, or a bare key when the dialect says so)
+ * Names are returned in the form they are matched in and values are
+ * unquoted. A header starts the section every following line belongs
+ * to, until the next header.
+ */
+func (d *decodeState) next() (event, bool) {
+	tok, raw, ok := d.token()
+	if !ok {
+		return event{}, false
+	}
+	d.trace(TraceEvent{Kind: TraceLine})
 
-	var section, nextSection property
-	var inSection, nextHasSection bool = false, false
-	var tempValue reflect.Value // "temp" is for filling in array of structs
-	var numTempValue int
+	ev := event{kind: lineKind(tok.Kind), name: tok.Name, value: tok.Value, raw: raw,
+		lineNum: d.lineNum, line: d.line}
 
-	d.generateMap(sectionMap, reflect.ValueOf(x))
+	switch ev.kind {
+	case lineKeyValue:
+		if d.dialect.ArrayKeys {
+			ev.name, ev.index = splitIndex(tok.Name)
+			ev.list = ev.name != tok.Name && len(ev.index) == 0
+		}
+		ev.name, ev.key = d.dialect.fold(ev.name), d.dialect.fold(tok.Name)
 
-	for d.scanner.Scan() {
-		if d.savedError != nil {
-			break
+	case lineHeader:
+		name := d.dialect.fold("[" + tok.Name + "]")
+		base := ""
+		if d.dialect.Inheritance {
+			name, base = splitBase(name)
+		}
+		profile := ""
+		if len(d.profile) > 0 {
+			name, profile = d.splitProfile(name)
+		}
+		if d.dialect.Subsections {
+			name = subsectionPath(name)
 		}
 
-		d.line = d.scanner.Text()
-		d.lineNum++
+		ev.name = name
+		d.section = sectionHeader{
+			name:    strings.TrimSpace(name[1 : len(name)-1]),
+			lineNum: d.lineNum,
+			line:    d.line,
+			profile: profile,
+			active:  len(profile) > 0 && profile == d.dialect.fold(d.profile),
+			base:    base,
+		}
+	}
 
-		//fmt.Printf("%03d: %s\n", d.lineNum, d.line)
+	ev.section = d.section
+	return ev, true
+}
 
-		line := strings.ToLower(strings.TrimSpace(d.line))
+/*
+ * Decodes the file into the value x points to: the lines read by next
+ * are bound onto a struct, or onto maps without a schema. Other values
+ * are an error.
+ */
+func (d *decodeState) unmarshal(x interface{}) error {
+	if isSchemaless(x) {
+		return d.bind(d.newAnyBinder(x))
+	}
 
-		if len(line) < 1 || line[0] == ';' || line[0] == '#' {
-			continue // skip comments
+	v := reflect.ValueOf(x)
+	if v.Kind() != reflect.Ptr {
+		return &IniError{0, "", fmt.Sprintf("Can't decode into non-pointer %T", x)}
+	} else if v.IsNil() {
+		return &IniError{0, "", fmt.Sprintf("Can't decode into nil %T", x)}
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
 		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return &IniError{0, "", fmt.Sprintf("Can't decode into value of type %s", v.Type())}
+	}
+	return d.bind(d.newStructBinder(x))
+}
 
-		// [Sections] could appear at any time (square brackets not required)
-		// When in a section, also look in children map
-		nextSection, nextHasSection = sectionMap[line]
-		if nextHasSection {
-			if numTempValue > 0 && section.isArray {
-				appendValue(section.value, tempValue)
-			}
+// Records the line of an event as unmatched, which is an error when
+// strict. DecodeEach keeps no lines, which would grow with the file,
+// and only traces and logs them.
+func (d *decodeState) unmatchedLine(ev event) {
+	if d.strict {
+		d.saveError(&IniError{ev.lineNum, ev.line, "Unmatched line"})
+		return
+	}
 
-			section = nextSection
-			inSection = true
+	if d.each == nil {
+		d.unmatched = append(d.unmatched, Unmatched{ev.lineNum, ev.line})
+	}
+	d.trace(TraceEvent{Kind: TraceUnmatched})
+	if d.logger != nil {
+		d.logger.Debug("unmatched line", "line", ev.lineNum, "text", ev.line)
+	}
+}
 
-			if section.isArray {
-				tempValue = reflect.New(section.value.Type().Elem())
-				d.generateMap(tempMap, tempValue)
-			}
+// Pushes the property map of a section entered.
+func (d *decodeState) pushScope(stack *PropMapStack, m propertyMap) {
+	stack.Push(m)
+	d.trace(TraceEvent{Kind: TracePush, Depth: stack.Size()})
+}
 
-			numTempValue = 0
-			continue
-		}
+// Pops the property map of the innermost section.
+func (d *decodeState) popScope(stack *PropMapStack) {
+	stack.Pop()
+	d.trace(TraceEvent{Kind: TracePop, Depth: stack.Size()})
+}
 
-		// unrecognized section - exit out of current section
-		if line[0] == '[' && line[len(line)-1] == ']' {
-			inSection = false
-			continue
-		}
+// Returns the key of the struct tags naming fields.
+func (d *decodeState) tag() string {
+	if len(d.tagName) == 0 {
+		return "ini"
+	}
+	return d.tagName
+}
 
-		matches := strings.SplitN(d.line, "=", 2)
-		matched := false
-
-		// potential property=value
-		if len(matches) == 2 {
-			n := strings.ToLower(strings.TrimSpace(matches[0]))
-			s := strings.TrimSpace(matches[1])
-
-			if inSection {
-				// child property, within a section
-				childProperty, hasProp := section.children[n]
-
-				if hasProp {
-					if section.isArray {
-						tempProperty := tempMap[n]
-						numTempValue++
-						d.setValue(tempProperty.value, s)
-					} else {
-						d.setValue(childProperty.value, s)
-					}
-
-					matched = true
-				}
-			}
+// Sets a property to a value read from the file or from a fallback.
+// Repeated keys append to a slice.
+func (d *decodeState) setProperty(prop property, ev event) {
+	if !d.override(prop, ev) || !d.keepKey(prop, ev) {
+		return
+	}
+	s := valueText(prop, ev)
+	d.trace(TraceEvent{Kind: TraceSet, Key: prop.tag, Value: s})
 
-			if !matched {
-				// top level property
-				topLevelProperty, hasProp := sectionMap[n]
-				if hasProp {
-					// just encountered a top level property - switch out of section mode
-					inSection = false
-					matched = true
-					d.setValue(topLevelProperty.value, s)
-				}
-			}
-		}
+	if prop.isMap {
+		d.setMapValue(prop, ev, s)
+		return
+	}
+
+	if !prop.isArray {
+		d.setValue(prop.value, s)
+		return
+	}
+
+	// keys of the section itself replace a list set by fallbacks
+	if k, ok := keyOf(prop.value); ok && d.inherited[k] {
+		d.resetList(prop)
+		delete(d.inherited, k)
+	}
+
+	if len(s) == 0 && d.dialect.EmptyResets {
+		d.resetList(prop)
+		return
+	}
+
+	if typ := prop.value.Type().Elem(); isList(typ) {
+		// one inner list per line, like a row of a matrix
+		value := reflect.New(typ).Elem()
+		d.setList(prop, value, s)
+		d.appendElement(prop, value)
+		return
+	}
 
-		if !matched {
-			d.unmatched = append(d.unmatched, Unmatched{d.lineNum, d.line})
+	if len(prop.split) > 0 {
+		elems, err := splitList(s, prop.split, prop.quoted)
+		if err != nil {
+			d.saveError(&IniError{ev.lineNum, ev.line, err.Error()})
+			return
 		}
+		for _, elem := range elems {
+			value := reflect.New(prop.value.Type().Elem())
+			d.setValue(reflect.Indirect(value), elem)
+			d.appendElement(prop, reflect.Indirect(value))
+		}
+		return
+	}
+
+	value := reflect.New(prop.value.Type().Elem())
+	d.setValue(reflect.Indirect(value), s)
+	d.appendElement(prop, reflect.Indirect(value))
+}
+
+// Returns the text a property is set to from an event: the value, or
+// for a RawValue the value as written.
+func valueText(prop property, ev event) string {
+	t := prop.value.Type()
+	if (prop.isArray || prop.isMap) && t != rawValueType {
+		t = t.Elem()
 	}
+	if t == rawValueType {
+		return ev.raw
+	}
+	return ev.value
+}
 
-	if numTempValue > 0 {
-		appendValue(section.value, tempValue)
+// Splits a key like limits[cpu] into limits and cpu, and extension[]
+// into extension and an empty index. Quotes around the index are removed.
+func splitIndex(key string) (string, string) {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return key, ""
 	}
 
-	return d.savedError
+	index := strings.TrimSpace(key[i+1 : len(key)-1])
+	if len(index) > 1 && (index[0] == '"' || index[0] == '\'') && index[len(index)-1] == index[0] {
+		index = index[1 : len(index)-1]
+	}
+	return strings.TrimSpace(key[:i]), index
+}
+
+// Sets the entry of a map field named by the index of a key like
+// limits[cpu].
+func (d *decodeState) setMapValue(prop property, ev event, s string) {
+	typ := prop.value.Type()
+	if typ.Key().Kind() != reflect.String {
+		d.saveError(&IniError{ev.lineNum, ev.line, fmt.Sprintf("Can't set map with key of type %s", typ.Key().Kind())})
+		return
+	} else if len(ev.index) == 0 {
+		d.saveError(&IniError{ev.lineNum, ev.line, "Missing map key"})
+		return
+	}
+
+	if prop.value.IsNil() {
+		prop.value.Set(reflect.MakeMap(typ))
+	}
+
+	value := reflect.New(typ.Elem()).Elem()
+	d.setValue(value, s)
+	prop.value.SetMapIndex(reflect.ValueOf(ev.index).Convert(typ.Key()), value)
 }
 
 func appendValue(arr, val reflect.Value) {
@@ -404,7 +789,20 @@ func appendValue(arr, val reflect.Value) {
 
 // Set Value with given string
 func (d *decodeState) setValue(v reflect.Value, s string) {
-	//fmt.Printf("SET(kind:%s, %s)\n", v.Kind(), s)
+
+	if d.valueHook != nil {
+		if ok, err := d.valueHook(v, s); err != nil {
+			d.saveError(&IniError{d.lineNum, d.line, err.Error()})
+			return
+		} else if ok {
+			return
+		}
+	}
+
+	if v.Type() == rawValueType {
+		v.SetBytes([]byte(s))
+		return
+	}
 
 	switch v.Kind() {
 
@@ -448,7 +846,6 @@ func (d *decodeState) setValue(v reflect.Value, s string) {
 }
 
 func (d *decodeState) sliceValue(v reflect.Value, s string) {
-	//fmt.Printf(":SLICE(%s, %s)\n", v.Kind(), s)
 
 	switch v.Type().Elem().Kind() {
 
@@ -502,11 +899,11 @@ func (d *decodeState) sliceValue(v reflect.Value, s string) {
 
 }
 
-// Returns true for truthy values like t/true/y/yes/1, false otherwise
+// Returns true for truthy values like t/true/y/yes/on/1, false otherwise
 func boolValue(s string) bool {
 	v := false
 	switch strings.ToLower(s) {
-	case "t", "true", "y", "yes", "1":
+	case "t", "true", "y", "yes", "on", "1":
 		v = true
 	}
 
@@ -519,31 +916,93 @@ type Decoder struct {
 	d decodeState
 }
 
-// NewDecoder returns a new decoder that reads from r.
+// NewDecoder returns a new decoder that reads from r, configured by
+// the options in order.
 //
-// The decoder introduces its own buffering and may
-// read data from r beyond the JSON values requested.
-func NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r: r}
+// The decoder reads r a line at a time as it decodes, through its own
+// buffer, unless the dialect has a DefaultSection or Inheritance: those
+// need the whole file ahead of decoding, which is then read into memory.
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	dec := &Decoder{r: r}
+	dec.d.dialect = DefaultDialect
+	for _, opt := range opts {
+		opt(dec)
+	}
+	return dec
 }
 
 // Decode reads the INI file and stores it in the value pointed to by v.
 //
 // See the documentation for Unmarshal for details about
 // the conversion of an INI into a Go value.
+//
+// An error of the reader is returned as an *IniError on the line being
+// read, which wraps it.
 func (dec *Decoder) Decode(v interface{}) error {
-
-	buf, readErr := ioutil.ReadAll(dec.r)
-	if readErr != nil {
-		return readErr
+	if err := dec.d.initReader(dec.r); err != nil {
+		return err
 	}
-	// Don't save err from unmarshal into dec.err:
-	// the connection is still usable since we read a complete JSON
-	// object from it before the error happened.
-	dec.d.init(buf)
-	err := dec.d.unmarshal(v)
+	return dec.d.unmarshal(v)
+}
+
+// DottedSections causes the Decoder to read a header such as
+// [server.tls] as a path of nested sections: the TLS section inside
+// the SERVER section. Without it, only sections whose tag has the
+// "dotted" option are resolved that way.
+func (dec *Decoder) DottedSections() {
+	dec.d.dotted = true
+}
+
+// Scoping changes how headers and keys are matched to sections from
+// the default rule, described at Scoping.
+func (dec *Decoder) Scoping(s Scoping) {
+	dec.d.scoping = s
+}
+
+// Dialect sets the syntax the Decoder reads. The default is
+// DefaultDialect.
+func (dec *Decoder) Dialect(dl Dialect) {
+	dec.d.dialect = dl
+}
+
+// Profile selects the profile whose sections are decoded. A section
+// header [NAME@profile] then overrides the keys of [NAME], wherever it
+// appears in the file, and sections of other profiles are skipped. A
+// section of a repeated section continues its last element, and [@profile]
+// overrides top level keys. The separator can be changed with the
+// dialect's ProfileSeparator.
+func (dec *Decoder) Profile(name string) {
+	dec.d.profile = name
+}
+
+// Duplicates sets what happens when a key of a scalar field or a
+// section that is not a slice of structs appears again, unless a field
+// sets its own with the tag option "dup", as in `ini:"Port,dup=error"`.
+// The default is the dialect's policy, which is DuplicateLast unless
+// set.
+func (dec *Decoder) Duplicates(p DuplicatePolicy) {
+	dec.d.duplicates = p
+}
+
+// OnDuplicate sets the function that is called for every duplicate
+// with the DuplicateWarn policy.
+func (dec *Decoder) OnDuplicate(fn func(Duplicate)) {
+	dec.d.onDuplicate = fn
+}
+
+// InferTypes makes the Decoder store values read into an interface{}
+// or map[string]interface{} as int64, float64 or bool when they read as
+// one, instead of as strings. true, yes and on, and false, no and off
+// are booleans.
+func (dec *Decoder) InferTypes() {
+	dec.d.inferTypes = true
+}
 
-	return err
+// Trace sets a Tracer that receives every step of decoding: lines
+// read, headers entered, section scopes pushed and popped, values set
+// and unmatched lines.
+func (dec *Decoder) Trace(t Tracer) {
+	dec.d.tracer = t
 }
 
 // UnparsedLines returns an array of strings where each string is an
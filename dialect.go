@@ -0,0 +1,270 @@
+package ini
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A Dialect describes the syntax of an INI file. Start from
+// DefaultDialect and change the fields that differ.
+type Dialect struct {
+	// Delimiters holds the characters that separate a key from its
+	// value. A line is split at the first one found. A space stands for
+	// any run of whitespace, which also absorbs another delimiter that
+	// follows it, so "Key = Value" still splits cleanly.
+	Delimiters string
+
+	// CommentPrefixes start a comment line.
+	CommentPrefixes []string
+
+	// RequireBrackets only reads [BRACKETED] lines as section headers.
+	// Other lines without a delimiter are unmatched, unless BareKeys.
+	RequireBrackets bool
+
+	// CaseSensitive matches keys and section names against tags
+	// exactly, instead of ignoring case.
+	CaseSensitive bool
+
+	// BareKeys reads an unbracketed line without a delimiter as a key
+	// with the value "true", instead of as a section header.
+	BareKeys bool
+
+	// Continuation selects how values continue over several lines.
+	Continuation Continuation
+
+	// Quoting selects how quoted values are read and written.
+	Quoting Quoting
+
+	// InlineComments removes comments that follow a value or header.
+	// The comment prefix must start the value or follow whitespace, and
+	// is ignored inside quotes.
+	InlineComments bool
+
+	// ArrayKeys reads "key[]=value" as a repeated key and
+	// "key[name]=value" as the entry name of a map[string]T field, like
+	// PHP. A key without brackets can't set a map field, and other fields
+	// only match a bracketed key as written, brackets included.
+	ArrayKeys bool
+
+	// EmptyResets clears the list of a repeated key when it is assigned
+	// an empty value, so "Key=" starts the list over.
+	EmptyResets bool
+
+	// DefaultSection names a section whose keys fill in every section
+	// that does not set them, like [DEFAULT] in Python's configparser.
+	// Empty disables it.
+	DefaultSection string
+
+	// Inheritance lets a section copy the keys of a base section, named
+	// in its header as [prod : base] or by an "extends=base" key. Keys
+	// of the section itself win over the base, which wins over DEFAULT.
+	Inheritance bool
+
+	// Subsections reads git-config headers like [remote "origin"] as the
+	// dotted path [remote.origin], see Decoder.DottedSections.
+	Subsections bool
+
+	// ProfileSeparator separates a section name from its profile in
+	// headers like [MYSQL@prod], see Decoder.Profile. Empty means "@".
+	ProfileSeparator string
+
+	// Duplicates selects what happens when a key of a scalar field or a
+	// section appears again, see Decoder.Duplicates. Zero keeps the
+	// last value.
+	Duplicates DuplicatePolicy
+}
+
+// DefaultDialect is the syntax read by Unmarshal and written by Marshal:
+// "=" delimiters, ";" and "#" comment lines, optional brackets on
+// section headers, case-insensitive names, "key[]" and "key[name]" keys,
+// and values taken literally, one line each.
+var DefaultDialect = Dialect{
+	Delimiters:      "=",
+	CommentPrefixes: []string{";", "#"},
+	ArrayKeys:       true,
+}
+
+// PHPDialect reads php.ini files: "key[]=" lists, quoted values with
+// escapes, ";" comments that may follow a value, and case sensitive
+// names. On/Off and yes/no read as booleans in every dialect.
+var PHPDialect = Dialect{
+	Delimiters:      "=",
+	CommentPrefixes: []string{";"},
+	RequireBrackets: true,
+	CaseSensitive:   true,
+	Quoting:         QuoteEscape,
+	InlineComments:  true,
+	ArrayKeys:       true,
+}
+
+// PythonDialect reads files like Python's configparser: "=" and ":"
+// delimiters, indented continuation lines and a DEFAULT section.
+var PythonDialect = Dialect{
+	Delimiters:      "=:",
+	CommentPrefixes: []string{"#", ";"},
+	RequireBrackets: true,
+	Continuation:    ContinueIndent,
+	DefaultSection:  "DEFAULT",
+}
+
+// SystemdDialect reads systemd unit files: case sensitive names,
+// repeated keys where an empty assignment resets the list, and
+// backslash continuation.
+var SystemdDialect = Dialect{
+	Delimiters:      "=",
+	CommentPrefixes: []string{"#", ";"},
+	RequireBrackets: true,
+	CaseSensitive:   true,
+	Continuation:    ContinueBackslash,
+	EmptyResets:     true,
+}
+
+// WindowsDialect reads files the way GetPrivateProfileString does:
+// ";" comments, case insensitive names, a single pair of quotes around a
+// value removed without escapes, and the first of duplicate keys and
+// sections.
+var WindowsDialect = Dialect{
+	Delimiters:      "=",
+	CommentPrefixes: []string{";"},
+	RequireBrackets: true,
+	Quoting:         QuoteStrip,
+	Duplicates:      DuplicateFirst,
+}
+
+// GitDialect reads git-config files: [section "subsection"] headers,
+// bare keys meaning true, quoted values with escapes, comments after
+// values and backslash continuation.
+var GitDialect = Dialect{
+	Delimiters:      "=",
+	CommentPrefixes: []string{"#", ";"},
+	RequireBrackets: true,
+	BareKeys:        true,
+	Continuation:    ContinueBackslash,
+	Quoting:         QuoteEscape,
+	InlineComments:  true,
+	Subsections:     true,
+}
+
+// Continuation selects the ways a value may continue over several
+// physical lines. Flags can be combined.
+type Continuation int
+
+const (
+	// ContinueHeredoc reads "KEY <<EOT" (or "KEY=<<EOT") and every
+	// following line up to a line holding only EOT as the value of KEY.
+	// Any identifier can be used in place of EOT.
+	ContinueHeredoc Continuation = 1 << iota
+
+	// ContinueBackslash joins a line ending in a backslash with the
+	// next line. The backslash is removed, nothing else is.
+	ContinueBackslash
+
+	// ContinueIndent appends indented lines following NAME=VALUE to the
+	// value, separated by newlines, like Python's configparser.
+	ContinueIndent
+)
+
+// Kinds of logical lines.
+type lineKind int
+
+const (
+	lineBlank lineKind = iota
+	lineComment
+	lineHeader
+	lineKeyValue
+	lineBare
+)
+
+// Classifies a trimmed logical line, returning the header or key name
+// and the value. Bare lines are headers unless the dialect has BareKeys.
+func (dl *Dialect) classify(line string) (kind lineKind, name, value string) {
+	if len(line) == 0 {
+		return lineBlank, "", ""
+	} else if dl.isComment(line) {
+		return lineComment, "", ""
+	}
+
+	header := line
+	if dl.InlineComments {
+		header = dl.stripComment(line)
+	}
+	if isBracketed(header) {
+		return lineHeader, header, ""
+	}
+
+	if name, value, ok := dl.split(line); ok {
+		return lineKeyValue, name, value
+	}
+
+	if dl.BareKeys {
+		return lineKeyValue, header, "true"
+	} else if dl.RequireBrackets {
+		return lineBare, header, ""
+	}
+	return lineHeader, header, ""
+}
+
+// Splits a line at the first delimiter into a trimmed key and value.
+func (dl *Dialect) split(line string) (name, value string, ok bool) {
+	delims := dl.Delimiters
+	if len(delims) == 0 {
+		delims = DefaultDialect.Delimiters
+	}
+
+	i := strings.IndexFunc(line, func(r rune) bool {
+		return strings.ContainsRune(delims, r) ||
+			(unicode.IsSpace(r) && strings.ContainsRune(delims, ' '))
+	})
+	if i <= 0 {
+		return "", "", false
+	}
+
+	name = strings.TrimSpace(line[:i])
+	rest := line[i:]
+	if unicode.IsSpace(rune(rest[0])) {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if len(rest) > 0 && rest[0] != ' ' && strings.IndexByte(delims, rest[0]) >= 0 {
+			rest = rest[1:]
+		}
+	} else {
+		rest = rest[1:]
+	}
+
+	return name, strings.TrimSpace(rest), true
+}
+
+// Reports whether a trimmed line is a comment.
+func (dl *Dialect) isComment(line string) bool {
+	for _, p := range dl.CommentPrefixes {
+		if len(p) > 0 && strings.HasPrefix(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes an inline comment from an unquoted value or header.
+func (dl *Dialect) stripComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') && dl.isComment(s[i:]) {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return s
+}
+
+// Returns the delimiter the Encoder writes between a key and its value.
+func (dl *Dialect) delimiter() string {
+	if len(dl.Delimiters) == 0 {
+		return DefaultDialect.Delimiters[:1]
+	}
+	return dl.Delimiters[:1]
+}
+
+// Returns a tag or key in the form it is matched in.
+func (dl *Dialect) fold(s string) string {
+	if dl.CaseSensitive {
+		return s
+	}
+	return strings.ToLower(s)
+}
@@ -0,0 +1,443 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDialectClassify(t *testing.T) {
+	dl := DefaultDialect
+	dl.Delimiters = ": ="
+	dl.CommentPrefixes = []string{"//", ";"}
+	dl.RequireBrackets = true
+
+	tests := []struct {
+		line  string
+		kind  lineKind
+		name  string
+		value string
+	}{
+		{"", lineBlank, "", ""},
+		{"// comment", lineComment, "", ""},
+		{"# not a comment", lineKeyValue, "#", "not a comment"},
+		{"[Section]", lineHeader, "[Section]", ""},
+		{"[a=b]", lineHeader, "[a=b]", ""},
+		{"key: value", lineKeyValue, "key", "value"},
+		{"key = value", lineKeyValue, "key", "value"},
+		{"key value", lineKeyValue, "key", "value"},
+		{"key\t\tvalue = 1", lineKeyValue, "key", "value = 1"},
+		{"url=http://x", lineKeyValue, "url", "http://x"},
+		{"Header", lineBare, "Header", ""},
+	}
+
+	for _, test := range tests {
+		kind, name, value := dl.classify(test.line)
+		if kind != test.kind || name != test.name || value != test.value {
+			t.Errorf("classify(%q) = %d %q %q, want %d %q %q", test.line,
+				kind, name, value, test.kind, test.name, test.value)
+		}
+	}
+}
+
+func TestDialect(t *testing.T) {
+	var d struct {
+		Main struct {
+			Host    string
+			Verbose bool
+			Debug   bool
+		} `ini:"[Main]"`
+		Other struct {
+			Host string
+		} `ini:"[main]"`
+	}
+
+	b := []byte(`
+// colon delimited, case sensitive
+[Main]
+Host: example.com
+Verbose
+host: ignored
+[main]
+Host: other.example.com
+NotAHeader
+`)
+
+	dl := DefaultDialect
+	dl.Delimiters = ":"
+	dl.CommentPrefixes = []string{"//"}
+	dl.RequireBrackets = true
+	dl.CaseSensitive = true
+	dl.BareKeys = true
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unmatched := dec.Unmatched()
+	if d.Main.Host != "example.com" {
+		t.Fatal("Main Host not set")
+	} else if d.Main.Verbose != true {
+		t.Fatal("Bare key Verbose not set")
+	} else if d.Other.Host != "other.example.com" {
+		t.Fatal("Other Host not set")
+	} else if len(unmatched) != 2 || unmatched[0].line != "host: ignored" || unmatched[1].line != "NotAHeader" {
+		t.Fatal("Unmatched lines incorrect:", unmatched)
+	}
+}
+
+func TestDialectEncode(t *testing.T) {
+	type doc struct {
+		Main struct {
+			Host  string
+			Notes string
+		} `ini:"[Main]"`
+	}
+
+	var d doc
+	d.Main.Host = "example.com"
+	d.Main.Notes = "one\ntwo"
+
+	dl := DefaultDialect
+	dl.Delimiters = ":="
+	dl.Continuation = ContinueIndent
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Dialect(dl)
+	if err := enc.Encode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[Main]\nHost:example.com\nNotes:one\n    two\n"
+	if buf.String() != expected {
+		t.Fatalf("Encode output incorrect:\n%s", buf.String())
+	}
+
+	var r doc
+	dec := NewDecoder(&buf)
+	dec.Dialect(dl)
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if r != d {
+		t.Fatalf("Round trip incorrect: %q", r)
+	}
+
+	d.Main.Notes = "one\n\nthree"
+	if err := enc.Encode(&d); err == nil {
+		t.Fatal("Expected error for value that can't be written as indented lines")
+	} else if err := NewEncoder(&buf).Encode(&d); err == nil {
+		t.Fatal("Expected error for multi-line value in DefaultDialect")
+	}
+}
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// Decodes every testdata/dialects/<preset>/*.ini file with its preset and
+// compares the result, as JSON, with the .golden file next to it.
+func TestDialectPresets(t *testing.T) {
+	presets := []struct {
+		dir     string
+		dialect Dialect
+		value   func() interface{}
+	}{
+		{"php", PHPDialect, func() interface{} {
+			return &struct {
+				PHP struct {
+					Engine       bool     `ini:"engine"`
+					ShortOpenTag bool     `ini:"short_open_tag"`
+					MemoryLimit  string   `ini:"memory_limit"`
+					ErrorLog     string   `ini:"error_log"`
+					Extensions   []string `ini:"extension"`
+				} `ini:"[PHP]"`
+				Session struct {
+					Name       string `ini:"session.name"`
+					UseCookies bool   `ini:"session.use_cookies"`
+				} `ini:"[Session]"`
+			}{}
+		}},
+		{"python", PythonDialect, func() interface{} {
+			return &struct {
+				Forge struct {
+					User                string
+					Description         string
+					ServerAliveInterval int
+					Compression         bool
+					CompressionLevel    int
+				} `ini:"[forge.example]"`
+				TopSecret struct {
+					Port                int
+					ForwardX11          bool
+					Compression         bool
+					ServerAliveInterval int
+				} `ini:"[topsecret.server.example]"`
+			}{}
+		}},
+		{"systemd", SystemdDialect, func() interface{} {
+			return &struct {
+				Unit struct {
+					Description string
+					After       []string
+				}
+				Service struct {
+					ExecStart   string
+					Environment []string
+					Restart     string
+				}
+				Install struct {
+					WantedBy string
+				}
+			}{}
+		}},
+		{"windows", WindowsDialect, func() interface{} {
+			return &struct {
+				Fonts struct{} `ini:"[fonts]"`
+				Mail  struct {
+					MAPI     int
+					MAPIX    int
+					MapiXVer string
+					Profile  string
+					Greeting string
+					Path     string
+				} `ini:"[mail]"`
+			}{}
+		}},
+		{"git", GitDialect, func() interface{} {
+			return &struct {
+				Core struct {
+					RepositoryFormatVersion int
+					FileMode                bool
+					Bare                    bool
+					LogAllRefUpdates        bool
+				}
+				Remote struct {
+					Origin struct {
+						URL   string
+						Fetch string
+					}
+				}
+				Branch struct {
+					Master struct {
+						Remote string
+						Merge  string
+					}
+				}
+				Alias struct {
+					Lg string
+				}
+			}{}
+		}},
+	}
+
+	for _, preset := range presets {
+		files, err := filepath.Glob(filepath.Join("testdata", "dialects", preset.dir, "*.ini"))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(files) == 0 {
+			t.Fatal("No test files for", preset.dir)
+		}
+
+		for _, file := range files {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v := preset.value()
+			dec := NewDecoder(bytes.NewReader(b))
+			dec.Dialect(preset.dialect)
+			if err := dec.Decode(v); err != nil {
+				t.Errorf("%s: %v", file, err)
+				continue
+			}
+
+			var unmatched []string
+			for _, u := range dec.Unmatched() {
+				unmatched = append(unmatched, u.String())
+			}
+
+			got, err := json.MarshalIndent(map[string]interface{}{
+				"value":     v,
+				"unmatched": unmatched,
+			}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := strings.TrimSuffix(file, ".ini") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: decoded\n%s\nwant\n%s", file, got, want)
+			}
+		}
+	}
+}
+
+func TestDefaultSection(t *testing.T) {
+	var d struct {
+		Songs []struct {
+			Title  string
+			Artist string
+			Tags   []string `ini:"Tag"`
+		} `ini:"[CREATE SONG]"`
+	}
+
+	b := []byte(`
+[DEFAULT]
+Artist=Unknown
+Tag=new
+Tag=unrated
+
+[CREATE SONG]
+Title=Long Way to Go
+Artist=The Coach
+
+[CREATE SONG]
+Title=The Falcon Lead
+Tag=live
+`)
+
+	dl := DefaultDialect
+	dl.DefaultSection = "DEFAULT"
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Songs) != 2 {
+		t.Fatal("Incorrect number of songs,", len(d.Songs))
+	} else if d.Songs[0].Artist != "The Coach" || d.Songs[1].Artist != "Unknown" {
+		t.Fatal("Artist fallback incorrect,", d.Songs)
+	} else if len(d.Songs[0].Tags) != 2 || d.Songs[0].Tags[1] != "unrated" {
+		t.Fatal("Songs[0] Tags incorrect,", d.Songs[0].Tags)
+	} else if len(d.Songs[1].Tags) != 1 || d.Songs[1].Tags[0] != "live" {
+		t.Fatal("Songs[1] Tags should replace the default,", d.Songs[1].Tags)
+	} else if len(dec.Unmatched()) != 0 {
+		t.Fatal("Unexpected unmatched lines,", dec.Unmatched())
+	}
+}
+
+func TestInheritance(t *testing.T) {
+	type database struct {
+		Host  string
+		Port  int
+		Users []string `ini:"User"`
+	}
+
+	var d struct {
+		Dev  database `ini:"[dev]"`
+		Prod database `ini:"[prod]"`
+		Jobs []struct {
+			Name     string
+			Host     string
+			Priority int
+		} `ini:"[job]"`
+	}
+
+	b := []byte(`
+[prod : base]
+Host=db.example.com
+User=admin
+
+[dev]
+extends=base
+
+[job : job defaults]
+Name=backup
+
+[job]
+Name=report
+Priority=1
+
+[base]
+Host=localhost
+Port=3306
+User=app
+User=reader
+
+[job defaults]
+extends = base
+Priority=5
+
+[DEFAULT]
+Port=5432
+`)
+
+	dl := DefaultDialect
+	dl.DefaultSection = "DEFAULT"
+	dl.Inheritance = true
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Prod.Host != "db.example.com" || d.Prod.Port != 3306 {
+		t.Fatal("Prod not inherited from base,", d.Prod)
+	} else if len(d.Prod.Users) != 1 || d.Prod.Users[0] != "admin" {
+		t.Fatal("Prod Users should replace the base,", d.Prod.Users)
+	} else if d.Dev.Host != "localhost" || len(d.Dev.Users) != 2 {
+		t.Fatal("Dev not inherited from base,", d.Dev)
+	} else if len(d.Jobs) != 2 {
+		t.Fatal("Incorrect number of jobs,", len(d.Jobs))
+	} else if d.Jobs[0].Name != "backup" || d.Jobs[0].Priority != 5 || d.Jobs[0].Host != "localhost" {
+		t.Fatal("Jobs[0] not inherited through job defaults,", d.Jobs[0])
+	} else if d.Jobs[1].Priority != 1 || d.Jobs[1].Host != "" {
+		t.Fatal("Jobs[1] should not inherit,", d.Jobs[1])
+	} else if len(dec.Unmatched()) != 0 {
+		t.Fatal("Unexpected unmatched lines,", dec.Unmatched())
+	}
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	var d struct {
+		A struct{ Host string } `ini:"[a]"`
+	}
+
+	dl := DefaultDialect
+	dl.Inheritance = true
+
+	tests := []struct {
+		ini string
+		err string
+	}{
+		{"[a : b]\nhost=x\n[b]\nextends=c\n[c : a]\n",
+			`Inheritance cycle a (line 1) -> b (line 3) -> c (line 5) -> a (line 1) on line 1: "[a : b]"`},
+		{"\n[a]\nextends = missing\n",
+			`Unknown base section "missing" on line 2: "[a]"`},
+	}
+
+	for _, test := range tests {
+		dec := NewDecoder(bytes.NewReader([]byte(test.ini)))
+		dec.Dialect(dl)
+		err := dec.Decode(&d)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Decode(%q) error = %v, want %s", test.ini, err, test.err)
+		}
+	}
+}
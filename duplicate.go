@@ -0,0 +1,131 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DuplicatePolicy selects what happens when a key of a scalar field, or
+// a section that is not a slice of structs, appears again.
+type DuplicatePolicy int
+
+const (
+	// DuplicateLast keeps the value read last. A repeated section adds
+	// its keys to the first. This is what happens when no policy is set.
+	DuplicateLast DuplicatePolicy = iota + 1
+
+	// DuplicateFirst keeps the value read first, and ignores the keys of
+	// a repeated section.
+	DuplicateFirst
+
+	// DuplicateError stops decoding with an error naming both lines.
+	DuplicateError
+
+	// DuplicateWarn keeps the value read last, like DuplicateLast, and
+	// reports the duplicate to the function set by Decoder.OnDuplicate.
+	DuplicateWarn
+)
+
+var duplicatePolicies = map[string]DuplicatePolicy{
+	"last":  DuplicateLast,
+	"first": DuplicateFirst,
+	"error": DuplicateError,
+	"warn":  DuplicateWarn,
+}
+
+// A Duplicate describes a key or section that appears again.
+type Duplicate struct {
+	Section   string // section of the key, or the repeated section
+	Key       string // empty for a repeated section
+	FirstLine int    // line that set the field first
+	LineNum   int    // line of the duplicate
+	Line      string
+}
+
+func (dup Duplicate) String() string {
+	name := "section [" + dup.Section + "]"
+	if len(dup.Key) > 0 {
+		name = "key " + dup.Key
+	}
+	return fmt.Sprintf("Duplicate %s (first on line %d)", name, dup.FirstLine)
+}
+
+// Reads the "dup" option of a field tag, returning zero if it is not
+// set.
+func (d *decodeState) parseDuplicates(opts tagOptions) DuplicatePolicy {
+	s, ok := opts.Get("dup")
+	if !ok {
+		return 0
+	}
+
+	p, ok := duplicatePolicies[strings.ToLower(s)]
+	if !ok {
+		d.saveError(&IniError{0, "", fmt.Sprintf("Invalid dup option %q", s)})
+	}
+	return p
+}
+
+// Returns the policy for a property: its own tag option, else the
+// Decoder's, else the dialect's.
+func (d *decodeState) duplicatePolicy(prop property) DuplicatePolicy {
+	for _, p := range []DuplicatePolicy{prop.duplicates, d.duplicates, d.dialect.Duplicates} {
+		if p != 0 {
+			return p
+		}
+	}
+	return DuplicateLast
+}
+
+/*
+ * Reports whether a scalar field may be set by the current line, given
+ * the line that set it first. Values from fallbacks and profiles are not
+ * duplicates, they are meant to be replaced.
+ */
+func (d *decodeState) keepKey(prop property, ev event) bool {
+	if d.inFallback || ev.section.active || prop.isArray || prop.isMap {
+		return true
+	}
+	return d.keepDuplicate(prop, Duplicate{Section: ev.section.name, Key: prop.tag, LineNum: ev.lineNum, Line: ev.line})
+}
+
+// Reports whether the keys of a section that was just entered are read.
+// The binder skips the keys of an ignored section like those of another
+// profile.
+func (d *decodeState) keepSection(prop property, ev event) bool {
+	if ev.section.active || prop.isArray {
+		return true
+	}
+	return d.keepDuplicate(prop, Duplicate{Section: ev.section.name, LineNum: ev.lineNum, Line: ev.line})
+}
+
+func (d *decodeState) keepDuplicate(prop property, dup Duplicate) bool {
+	k, ok := keyOf(prop.value)
+	if !ok {
+		return true
+	}
+
+	first, seen := d.seen[k]
+	if !seen {
+		if d.seen == nil {
+			d.seen = make(map[fieldKey]int)
+		}
+		d.seen[k] = dup.LineNum
+		return true
+	}
+
+	dup.FirstLine = first
+	switch d.duplicatePolicy(prop) {
+	case DuplicateFirst:
+		return false
+	case DuplicateError:
+		d.saveError(&IniError{dup.LineNum, dup.Line, dup.String()})
+		return false
+	case DuplicateWarn:
+		if d.onDuplicate != nil {
+			d.onDuplicate(dup)
+		} else if d.logger != nil {
+			d.logger.Warn(dup.String(), "line", dup.LineNum, "text", dup.Line)
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package ini
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// Stop can be returned by the function given to DecodeEach to stop
+// decoding early. DecodeEach then returns nil.
+var Stop = errors.New("stop decoding")
+
+// An eachSection is the section DecodeEach hands over one at a time.
+type eachSection struct {
+	value   reflect.Value
+	fn      func() error
+	pending bool // value holds a section not handed over yet
+}
+
+/*
+ * DecodeEach decodes every section [name] of the file read by dec into
+ * the same value of type T, a struct, and calls fn with it once the
+ * section ends, so a file of any number of sections is decoded in
+ * little memory. The value is cleared for every section and is only
+ * valid until fn returns; copy it to keep it.
+ *
+ * Decoding stops at the first error, which is returned. An error
+ * returned by fn is returned as it is, except for Stop, which stops
+ * decoding without an error. Lines of other sections are unmatched, and
+ * a profile section continues the section it follows. Unmatched lines
+ * are not kept, so Decoder.Unmatched stays empty, but they are sent to
+ * the Tracer and Logger, if any, and are errors with WithStrict.
+ */
+func DecodeEach[T any](dec *Decoder, name string, fn func(v *T) error) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("ini: DecodeEach needs a struct, not %s", t)
+	}
+
+	v := reflect.New(sectionOf(t, name, dec.d.tag()))
+	section := v.Elem().Field(0)
+	ptr := section.Addr().Interface().(*T)
+
+	dec.d.each = &eachSection{value: section, fn: func() error { return fn(ptr) }}
+	defer func() { dec.d.each = nil }()
+
+	err := dec.Decode(v.Interface())
+	if errors.Is(err, Stop) {
+		return nil
+	}
+	return err
+}
+
+/*
+ * Sections returns an iterator over the sections [name] of the file
+ * read by dec, decoded one at a time like DecodeEach does. Decoding
+ * stops when the loop ends, and an error is yielded last:
+ *
+ *	for song, err := range ini.Sections[Song](dec, "CREATE SONG") {
+ *		if err != nil {
+ *			return err
+ *		}
+ *		...
+ *	}
+ */
+func Sections[T any](dec *Decoder, name string) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := DecodeEach(dec, name, func(v *T) error {
+			if !yield(v, nil) {
+				return Stop
+			}
+			return nil
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Starts a new section of DecodeEach when its header is read, handing
+// over the one before.
+func (d *decodeState) nextEach(prop property) {
+	if d.each == nil {
+		return
+	}
+	k, ok := keyOf(prop.value)
+	if want, _ := keyOf(d.each.value); !ok || k != want {
+		return
+	}
+
+	d.flushEach()
+	prop.value.Set(reflect.Zero(prop.value.Type()))
+	d.forgetFields(prop.value)
+
+	// embedded pointers are allocated again
+	for k := range prop.children {
+		delete(prop.children, k)
+	}
+	d.generateMap(prop.children, prop.value)
+	d.each.pending = true
+}
+
+// Hands the section decoded so far to the function of DecodeEach.
+func (d *decodeState) flushEach() {
+	if d.each == nil || !d.each.pending {
+		return
+	}
+	d.each.pending = false
+
+	d.checkCounts()
+	if d.savedError != nil {
+		return
+	}
+	if err := d.each.fn(); err != nil {
+		d.saveError(err)
+	}
+}
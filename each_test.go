@@ -0,0 +1,161 @@
+package ini
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type eachSong struct {
+	SongId int
+	Title  string
+	Tags   []string `ini:"Tag"`
+	Rating [2]int   `ini:"Rating,exact"`
+}
+
+var eachSongs = []byte(`
+[CREATE SONG]
+SongId=21348
+Title=Long Way to Go
+Tag=rock
+Tag=live
+Rating=4
+Rating=5
+
+[CREATE PLAYLIST]
+Title=Acid Jazz
+
+[CREATE SONG]
+SongId=9855
+Rating=3
+Rating=3
+
+[CREATE SONG]
+SongId=7558
+Title=Lounge
+Rating=1
+Rating=2
+`)
+
+func TestDecodeEach(t *testing.T) {
+	var got []eachSong
+	var ptrs []*eachSong
+	unmatched := 0
+	dec := NewDecoder(strings.NewReader(string(eachSongs)), WithTracer(TracerFunc(func(ev TraceEvent) {
+		if ev.Kind == TraceUnmatched {
+			unmatched++
+		}
+	})))
+	err := DecodeEach(dec, "[CREATE SONG]", func(s *eachSong) error {
+		got = append(got, *s)
+		ptrs = append(ptrs, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 3 {
+		t.Fatal("Incorrect number of songs,", got)
+	} else if got[0].SongId != 21348 || len(got[0].Tags) != 2 || got[0].Rating != [2]int{4, 5} {
+		t.Fatal("Song 1 incorrect,", got[0])
+	} else if got[1].SongId != 9855 || got[1].Title != "" || len(got[1].Tags) != 0 {
+		t.Fatal("Song 2 not cleared,", got[1])
+	} else if got[2].Title != "Lounge" || got[2].Rating != [2]int{1, 2} {
+		t.Fatal("Song 3 incorrect,", got[2])
+	} else if ptrs[0] != ptrs[2] {
+		t.Fatal("Section value not reused")
+	} else if unmatched != 2 || len(dec.Unmatched()) != 0 {
+		t.Fatal("Expected playlist lines unmatched and not kept,", unmatched, dec.Unmatched())
+	}
+
+	got = nil
+	dec = NewDecoder(strings.NewReader(string(eachSongs)))
+	err = DecodeEach(dec, "CREATE SONG", func(s *eachSong) error {
+		got = append(got, *s)
+		if len(got) == 2 {
+			return Stop
+		}
+		return nil
+	})
+	if err != nil || len(got) != 2 {
+		t.Fatal("Expected to stop after 2 songs,", len(got), err)
+	}
+
+	b := strings.Replace(string(eachSongs), "Rating=1\n", "", 1)
+	got = nil
+	err = DecodeEach(NewDecoder(strings.NewReader(b)), "CREATE SONG", func(s *eachSong) error {
+		got = append(got, *s)
+		return nil
+	})
+	if err == nil || err.Error() != `Expected 2 values for rating, got 1 on line 21: "Rating=2"` {
+		t.Fatal("Expected error on the line of the last song,", err)
+	} else if len(got) != 2 {
+		t.Fatal("Songs before the error not handed over,", got)
+	}
+
+	failed := fmt.Errorf("full")
+	err = DecodeEach(NewDecoder(strings.NewReader(string(eachSongs))), "CREATE SONG", func(s *eachSong) error {
+		return failed
+	})
+	if err != failed {
+		t.Fatal("Expected error of the function,", err)
+	}
+
+	err = DecodeEach(NewDecoder(strings.NewReader("")), "CREATE SONG", func(s *[]eachSong) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected error for a slice")
+	}
+}
+
+func TestSections(t *testing.T) {
+	var ids []int
+	for song, err := range Sections[eachSong](NewDecoder(strings.NewReader(string(eachSongs))), "CREATE SONG") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, song.SongId)
+		if len(ids) == 2 {
+			break
+		}
+	}
+
+	if len(ids) != 2 || ids[1] != 9855 {
+		t.Fatal("Incorrect songs,", ids)
+	}
+
+	b := strings.Replace(string(eachSongs), "SongId=9855", "SongId=x", 1)
+	var last error
+	ids = nil
+	for song, err := range Sections[eachSong](NewDecoder(strings.NewReader(b)), "CREATE SONG") {
+		if err != nil {
+			last = err
+			continue
+		}
+		ids = append(ids, song.SongId)
+	}
+
+	if len(ids) != 1 || last == nil || !strings.HasSuffix(last.Error(), `on line 14: "SongId=x"`) {
+		t.Fatal("Expected error after first song,", ids, last)
+	}
+}
+
+func TestDecodeEachTagName(t *testing.T) {
+	type song struct {
+		Id int `cfg:"SongId"`
+	}
+
+	var ids []int
+	dec := NewDecoder(strings.NewReader(string(eachSongs)), WithTagName("cfg"))
+	err := DecodeEach(dec, "CREATE SONG", func(s *song) error {
+		ids = append(ids, s.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(ids) != "[21348 9855 7558]" {
+		t.Fatal("Sections with another tag name incorrect,", ids)
+	}
+}
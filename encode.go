@@ -0,0 +1,412 @@
+// Encode Go structs as INI files, the reverse of Unmarshal
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Marshal returns the INI encoding of v, which must be a struct or
+ * a pointer to a struct.
+ *
+ * Fields are named by their ini tag, or the field name when there is
+ * none. Scalar fields of a struct are written before its sections so
+ * that Unmarshal reads every key back into the same section. A slice of
+ * scalars is written as a repeated key, or as one line with the "split"
+ * tag option, and a slice of structs as a repeated section. Strings
+ * that DefaultDialect can't read back, with newlines or surrounding
+ * spaces, are an error. If v implements Marshaler, its MarshalINI method
+ * encodes it instead.
+ */
+func Marshal(v interface{}) ([]byte, error) {
+	if m, ok := v.(Marshaler); ok {
+		return m.MarshalINI()
+	}
+
+	e := encodeState{dialect: DefaultDialect}
+	if err := e.marshal(v); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// An Encoder writes INI files to an output stream.
+type Encoder struct {
+	w       io.Writer
+	dialect Dialect
+	profile string
+	base    interface{}
+
+	arrayKeys bool
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, dialect: DefaultDialect}
+}
+
+// Encode writes the INI encoding of v to the stream.
+//
+// See the documentation for Marshal for details about the
+// conversion of a Go value to INI.
+func (enc *Encoder) Encode(v interface{}) error {
+	e := encodeState{dialect: enc.dialect, profile: enc.profile, arrayKeys: enc.arrayKeys}
+	if len(enc.profile) > 0 {
+		if err := e.marshalProfile(v, enc.base); err != nil {
+			return err
+		}
+	} else if err := e.marshal(v); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(e.Bytes())
+	return err
+}
+
+// Dialect sets the syntax the Encoder writes. The default is
+// DefaultDialect.
+//
+// Keys are separated from values by the first of the dialect's
+// delimiters. With quoting, strings that would not read back unchanged,
+// such as values with surrounding whitespace, comment characters or
+// newlines, are written in double quotes with escapes. Otherwise
+// multi-line strings are written as heredocs or indented continuation
+// lines, whichever the dialect reads, and strings with surrounding
+// whitespace as heredocs. Strings the dialect can't read back are an
+// error.
+func (enc *Encoder) Dialect(dl Dialect) {
+	enc.dialect = dl
+}
+
+// ArrayKeys makes the Encoder write slices of scalars PHP style, as
+// "key[]=value" lines, instead of as repeated keys. Maps are always
+// written as "key[name]=value" lines, sorted by name.
+func (enc *Encoder) ArrayKeys(on bool) {
+	enc.arrayKeys = on
+}
+
+// Profile makes the Encoder write a profile for the Decoder's Profile:
+// only the values of v that differ from base are written, in sections
+// named [NAME@profile]. Base must have the same type as the values
+// encoded. A list the profile empties is written as an empty key, so
+// only a dialect with EmptyResets can encode it; an empty map is an
+// error. An empty name writes whole files again.
+func (enc *Encoder) Profile(name string, base interface{}) {
+	enc.profile = name
+	enc.base = base
+}
+
+// encodeState encodes a single INI file into its buffer.
+type encodeState struct {
+	bytes.Buffer
+	err     error
+	dialect Dialect
+	profile string
+	pending []string // profile headers not written yet
+
+	arrayKeys bool
+	template  bool // see Template
+}
+
+func (e *encodeState) marshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return &IniError{0, "", "Can't encode nil value"}
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return &IniError{0, "", fmt.Sprintf("Can't encode value of type %s", rv.Kind())}
+	}
+
+	e.writeKeys(rv)
+	e.writeSections(rv, "")
+	return e.err
+}
+
+// encodeField describes a struct field the way generateMap sees it.
+type encodeField struct {
+	name      string
+	value     reflect.Value
+	opts      tagOptions
+	isSection bool
+	doc       string
+	def       string
+}
+
+func encodeFields(v reflect.Value) []encodeField {
+	var fields []encodeField
+	for _, tf := range cachedTypeFields(v.Type(), "ini") {
+		f, ok := fieldByIndex(v, tf.index, false)
+		if !ok {
+			continue // in a nil embedded struct
+		}
+		kind := f.Kind()
+
+		fields = append(fields, encodeField{
+			name:  tf.name,
+			value: f,
+			opts:  tf.opts,
+			doc:   tf.doc,
+			def:   tf.def,
+			isSection: kind == reflect.Struct ||
+				(kind == reflect.Slice && f.Type().Elem().Kind() == reflect.Struct),
+		})
+	}
+	return fields
+}
+
+// Writes the NAME=VALUE lines of a struct.
+func (e *encodeState) writeKeys(v reflect.Value) {
+	for _, f := range encodeFields(v) {
+		if !f.isSection {
+			if e.template {
+				e.writeComment(f.doc)
+				if len(f.def) > 0 && f.value.IsZero() {
+					f.value = e.defaultValue(f)
+				}
+				f.value = exampleValue(f.value, f.opts)
+			}
+			e.writeField(f)
+		}
+	}
+}
+
+// Writes the lines of a field that is not a section: one line for a
+// scalar or a split list, or one for each element of a slice or entry of
+// a map.
+func (e *encodeState) writeField(f encodeField) {
+	switch {
+	case f.value.Kind() == reflect.Map:
+		if f.value.Type().Key().Kind() != reflect.String {
+			e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode map with key of type %s", f.value.Type().Key().Kind())})
+			return
+		}
+		keys := f.value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			e.writeValue(f.name+"["+k.String()+"]", f.value.MapIndex(k))
+		}
+
+	case isList(f.value.Type()):
+		name := f.name
+		sep, split := f.opts.Get("split")
+		quoted := f.opts.Contains("quoted")
+		if isList(f.value.Type().Elem()) {
+			// one line for each inner list
+			if !split {
+				sep = ","
+			}
+			for i := 0; i < f.value.Len(); i++ {
+				e.writeList(name, sep, f.value.Index(i), quoted)
+			}
+			return
+		} else if split {
+			e.writeList(name, sep, f.value, quoted)
+			return
+		} else if e.arrayKeys {
+			name += "[]"
+		}
+		for i := 0; i < f.value.Len(); i++ {
+			e.writeValue(name, f.value.Index(i))
+		}
+
+	default:
+		e.writeValue(f.name, f.value)
+	}
+}
+
+/*
+ * Writes the sections of a struct, each followed by its own sections.
+ * Inside a section with the "dotted" option, nested headers are written
+ * as their full path, for example [server.tls].
+ */
+func (e *encodeState) writeSections(v reflect.Value, path string) {
+	for _, f := range encodeFields(v) {
+		if !f.isSection {
+			continue
+		}
+
+		name := f.name
+		if isBracketed(name) {
+			name = name[1 : len(name)-1]
+		}
+
+		childPath := ""
+		if len(path) > 0 {
+			name = path + "." + name
+			childPath = name
+		} else if f.opts.Contains("dotted") {
+			childPath = name
+		}
+
+		if e.template {
+			e.writeExampleSection(f, name, childPath)
+		} else if f.value.Kind() == reflect.Slice {
+			for i := 0; i < f.value.Len(); i++ {
+				e.writeSection(name, childPath, f.value.Index(i))
+			}
+		} else {
+			e.writeSection(name, childPath, f.value)
+		}
+	}
+}
+
+func (e *encodeState) writeSection(name, path string, v reflect.Value) {
+	if e.Len() > 0 {
+		e.WriteByte('\n')
+	}
+	fmt.Fprintf(e, "[%s]\n", name)
+	e.writeKeys(v)
+	e.writeSections(v, path)
+}
+
+// Writes a single NAME=VALUE line, or a heredoc for strings that need one.
+func (e *encodeState) writeValue(name string, v reflect.Value) {
+	if v.Type() == rawValueType {
+		e.writeLine(name, string(v.Bytes()))
+		return
+	}
+
+	s, ok := formatValue(v)
+	if !ok {
+		e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode value of type %s", v.Kind())})
+		return
+	}
+
+	if v.Kind() == reflect.String {
+		if e.dialect.Quoting == QuoteStrip {
+			if s != strings.TrimSpace(s) || strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") || e.opensHeredoc(s) {
+				s = `"` + s + `"`
+			}
+		} else if e.dialect.Quoting != QuoteNone && (needsQuotes(s) || e.opensHeredoc(s)) {
+			s = quote(s)
+		}
+		if strings.Contains(s, "\n") || e.opensHeredoc(s) {
+			e.writeMultiline(name, s)
+			return
+		} else if s != strings.TrimSpace(s) {
+			// without quotes, only a heredoc keeps the spaces
+			if e.dialect.Continuation&ContinueHeredoc == 0 {
+				e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode value of %s with surrounding spaces in this dialect", name)})
+				return
+			}
+			e.writeMultiline(name, s)
+			return
+		}
+	}
+
+	e.writeLine(name, s)
+}
+
+// Reports whether a value written as is, like <<EOT, would be read
+// back as the start of a heredoc.
+func (e *encodeState) opensHeredoc(s string) bool {
+	s = strings.TrimSpace(s)
+	return e.dialect.Continuation&ContinueHeredoc != 0 &&
+		strings.HasPrefix(s, "<<") && isIdentifier(strings.TrimSpace(s[2:]))
+}
+
+// Returns the text of a scalar value, before any quoting.
+func formatValue(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+
+	case reflect.String:
+		return v.String(), true
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true
+	}
+
+	return "", false
+}
+
+// Writes a slice or array as a list on one line, for fields with the
+// split option and the inner lists of [][]T.
+func (e *encodeState) writeList(name, sep string, v reflect.Value, quoted bool) {
+	elems := make([]string, v.Len())
+	for i := range elems {
+		s, ok := formatValue(v.Index(i))
+		if !ok {
+			e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode value of type %s", v.Index(i).Kind())})
+			return
+		}
+		elems[i] = s
+	}
+
+	s, ok := joinList(elems, sep, quoted)
+	if !ok {
+		e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode element of %s containing its separator", name)})
+		return
+	}
+	e.writeValue(name, reflect.ValueOf(s))
+}
+
+func (e *encodeState) writeLine(name, value string) {
+	fmt.Fprintf(e, "%s%s%s\n", name, e.dialect.delimiter(), value)
+}
+
+// Writes a value containing newlines in a form the dialect reads back.
+func (e *encodeState) writeMultiline(name, s string) {
+	if e.dialect.Continuation&ContinueHeredoc != 0 {
+		tag := heredocTag(s)
+		fmt.Fprintf(e, "%s <<%s\n%s\n%s\n", name, tag, s, tag)
+	} else if e.dialect.Continuation&ContinueIndent != 0 && indentable(s) {
+		e.writeLine(name, strings.Replace(s, "\n", "\n    ", -1))
+	} else {
+		e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode multi-line value of %s in this dialect", name)})
+	}
+}
+
+// Reports whether indented continuation lines read back as s: every line
+// but the first must be non-empty without surrounding whitespace.
+func indentable(s string) bool {
+	lines := strings.Split(s, "\n")
+	for _, line := range lines[1:] {
+		if len(line) == 0 || line != strings.TrimSpace(line) {
+			return false
+		}
+	}
+	return lines[0] == strings.TrimSpace(lines[0])
+}
+
+// Returns a heredoc terminator that does not appear as a line of s.
+func heredocTag(s string) string {
+	lines := strings.Split(s, "\n")
+	tag := "EOT"
+	for n := 1; ; n++ {
+		found := false
+		for _, line := range lines {
+			if strings.TrimSpace(line) == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return tag
+		}
+		tag = "EOT" + strconv.Itoa(n)
+	}
+}
+
+func (e *encodeState) saveError(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
@@ -0,0 +1,195 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// Writes the values of v that differ from base as profile sections.
+func (e *encodeState) marshalProfile(v, base interface{}) error {
+	rv, rb := reflect.ValueOf(v), reflect.ValueOf(base)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return &IniError{0, "", "Can't encode nil value"}
+		}
+		rv = rv.Elem()
+	}
+	for rb.Kind() == reflect.Ptr || rb.Kind() == reflect.Interface {
+		if rb.IsNil() {
+			return &IniError{0, "", "Can't encode nil profile base"}
+		}
+		rb = rb.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return &IniError{0, "", fmt.Sprintf("Can't encode value of type %s", rv.Kind())}
+	} else if rb.Type() != rv.Type() {
+		return &IniError{0, "", fmt.Sprintf("Profile base must have type %s", rv.Type())}
+	}
+
+	e.writeOverrides(rv, rb, "", "")
+	return e.err
+}
+
+/*
+ * Writes the section name of a profile, holding the keys that differ
+ * from base, followed by its own sections. A header is only written
+ * once a key is, together with the headers of the sections around it,
+ * so nested sections are found when read back.
+ */
+func (e *encodeState) writeOverrides(v, base reflect.Value, name, path string) {
+	n := len(e.pending)
+	e.pending = append(e.pending, name)
+
+	e.writeKeyOverrides(v, base)
+	e.writeSectionOverrides(v, base, path)
+
+	if len(e.pending) > n {
+		e.pending = e.pending[:n]
+	}
+}
+
+func (e *encodeState) writeKeyOverrides(v, base reflect.Value) {
+	for _, f := range encodeFields(v) {
+		if !f.isSection && !sameValue(f.value, baseField(base, f)) {
+			// lists and maps are written whole, the decoder starts
+			// them over at their first key
+			e.writePending()
+			if writesNoKeys(f) {
+				e.writeReset(f)
+			} else {
+				e.writeField(f)
+			}
+		}
+	}
+}
+
+// Reports whether a field is an empty list or map written as no keys at
+// all, which would leave the value of the base in place when read back.
+// A list with the split option is written as an empty value instead.
+func writesNoKeys(f encodeField) bool {
+	t := f.value.Type()
+	if f.value.Kind() == reflect.Map {
+		return f.value.Len() == 0
+	} else if !isList(t) || f.value.Kind() != reflect.Slice || f.value.Len() > 0 {
+		return false
+	}
+	_, split := f.opts.Get("split")
+	return !split || isList(t.Elem())
+}
+
+// Writes an empty key that clears a list, which only a dialect with
+// EmptyResets reads back that way. Other dialects and maps can't be
+// cleared by a profile.
+func (e *encodeState) writeReset(f encodeField) {
+	if f.value.Kind() == reflect.Map {
+		e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode profile emptying map %s", f.name)})
+	} else if !e.dialect.EmptyResets {
+		e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode profile emptying list %s in this dialect", f.name)})
+	} else {
+		e.writeLine(f.name, "")
+	}
+}
+
+func (e *encodeState) writeSectionOverrides(v, base reflect.Value, path string) {
+	for _, f := range encodeFields(v) {
+		bv := baseField(base, f)
+		if !f.isSection || sameValue(f.value, bv) {
+			continue
+		}
+
+		name := f.name
+		if isBracketed(name) {
+			name = name[1 : len(name)-1]
+		}
+
+		childPath := ""
+		if len(path) > 0 {
+			name = path + "." + name
+			childPath = name
+		} else if f.opts.Contains("dotted") {
+			childPath = name
+		}
+
+		if f.value.Kind() == reflect.Slice {
+			e.saveError(&IniError{0, "", fmt.Sprintf("Can't encode profile of repeated section %s", name)})
+			continue
+		}
+		e.writeOverrides(f.value, bv, name, childPath)
+	}
+}
+
+// Returns the field of base matching a field of the value encoded, or
+// its zero value when it is in a nil embedded struct of base.
+func baseField(base reflect.Value, f encodeField) reflect.Value {
+	for _, bf := range encodeFields(base) {
+		if bf.name == f.name {
+			return bf.value
+		}
+	}
+	return reflect.Zero(f.value.Type())
+}
+
+// Writes the headers of the sections entered since the last key. The
+// top level header [@profile] is only written for keys of its own.
+func (e *encodeState) writePending() {
+	for i, name := range e.pending {
+		if len(name) == 0 && i < len(e.pending)-1 {
+			continue
+		}
+		if e.Len() > 0 {
+			e.WriteByte('\n')
+		}
+		fmt.Fprintf(e, "[%s%s%s]\n", name, e.dialect.profileSeparator(), e.profile)
+	}
+	e.pending = e.pending[:0]
+}
+
+// Reports whether two values of the same type hold the same data.
+func sameValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !sameValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Slice, reflect.Array:
+		if a.Type() == rawValueType {
+			return bytes.Equal(a.Bytes(), b.Bytes())
+		} else if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameValue(a.Elem(), b.Elem())
+
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, k := range a.MapKeys() {
+			if bv := b.MapIndex(k); !bv.IsValid() || !sameValue(a.MapIndex(k), bv) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	}
+
+	return a.Equal(b)
+}
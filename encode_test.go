@@ -0,0 +1,387 @@
+package ini
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type tunePlayer struct {
+	Name  string `ini:"NAME"`
+	Songs []struct {
+		SongId int
+		Title  string
+		Artist string
+	} `ini:"[CREATE SONG]"`
+
+	Playlists []struct {
+		PlaylistId int
+		Title      string
+		SongIds    []int `ini:"Song"`
+	} `ini:"[CREATE PLAYLIST]"`
+}
+
+func TestMarshal(t *testing.T) {
+	var d tunePlayer
+
+	d.Name = "Tunes"
+	d.Songs = make([]struct {
+		SongId int
+		Title  string
+		Artist string
+	}, 2)
+	d.Songs[0].SongId = 21348
+	d.Songs[0].Title = "Long Way to Go"
+	d.Songs[1].SongId = 9855
+	d.Songs[1].Artist = "It Wasn't Safe"
+	d.Playlists = make([]struct {
+		PlaylistId int
+		Title      string
+		SongIds    []int `ini:"Song"`
+	}, 1)
+	d.Playlists[0].PlaylistId = 438432
+	d.Playlists[0].SongIds = []int{21348, 482}
+
+	b, err := Marshal(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `NAME=Tunes
+
+[CREATE SONG]
+SongId=21348
+Title=Long Way to Go
+Artist=
+
+[CREATE SONG]
+SongId=9855
+Title=
+Artist=It Wasn't Safe
+
+[CREATE PLAYLIST]
+PlaylistId=438432
+Title=
+Song=21348
+Song=482
+`
+	if string(b) != expected {
+		t.Fatalf("Marshal output incorrect:\n%s", b)
+	}
+
+	var r tunePlayer
+	if err := Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Name != "Tunes" || len(r.Songs) != 2 || len(r.Playlists) != 1 {
+		t.Fatal("Round trip lost values:", r)
+	} else if r.Songs[1].Artist != "It Wasn't Safe" {
+		t.Fatal("Round trip Songs[1] Artist incorrect")
+	} else if len(r.Playlists[0].SongIds) != 2 || r.Playlists[0].SongIds[1] != 482 {
+		t.Fatal("Round trip Playlists[0] SongIds incorrect")
+	}
+}
+
+func TestMarshalMultiline(t *testing.T) {
+	type doc struct {
+		Cert  string `ini:"CERT"`
+		Notes string `ini:"NOTES"`
+	}
+
+	d := doc{"-----BEGIN-----\nMIIB\n-----END-----", "EOT\nends early?\n"}
+
+	if _, err := Marshal(d); err == nil || err.Error() != "Can't encode multi-line value of CERT in this dialect" {
+		t.Fatal("Expected error for multi-line value in DefaultDialect,", err)
+	}
+
+	dl := DefaultDialect
+	dl.Continuation = ContinueHeredoc
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Dialect(dl)
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+
+	var r doc
+	dec := NewDecoder(&buf, WithDialect(dl))
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	if r != d {
+		t.Fatalf("Multi-line round trip incorrect: %q", r)
+	}
+
+	// surrounding spaces only read back from quotes or a heredoc
+	d = doc{"  spaced  ", "one"}
+	if _, err := Marshal(d); err == nil || err.Error() != "Can't encode value of CERT with surrounding spaces in this dialect" {
+		t.Fatal("Expected error for value with surrounding spaces,", err)
+	}
+
+	buf.Reset()
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	} else if err := NewDecoder(&buf, WithDialect(dl)).Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if r != d {
+		t.Fatalf("Spaced value round trip incorrect: %q", r)
+	}
+}
+
+func TestMarshalDotted(t *testing.T) {
+	var d struct {
+		Server struct {
+			Host string `ini:"host"`
+			TLS  struct {
+				Cert string `ini:"cert"`
+			} `ini:"[tls]"`
+		} `ini:"[server],dotted"`
+	}
+	d.Server.Host = "example.com"
+	d.Server.TLS.Cert = "server.pem"
+
+	b, err := Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[server]\nhost=example.com\n\n[server.tls]\ncert=server.pem\n"
+	if string(b) != expected {
+		t.Fatalf("Marshal output incorrect:\n%s", b)
+	}
+}
+
+func TestMarshalQuoted(t *testing.T) {
+	type doc struct {
+		Name  string `ini:"NAME"`
+		Notes string `ini:"NOTES"`
+		Raw   RawValue
+	}
+
+	d := doc{"  Rock & Roll ; live", "line one\nline \"two\"", RawValue(`'as is'`)}
+
+	dl := DefaultDialect
+	dl.Quoting = QuoteEscape
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Dialect(dl)
+	if err := enc.Encode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `NAME="  Rock & Roll ; live"
+NOTES="line one\nline \"two\""
+Raw='as is'
+`
+	if buf.String() != expected {
+		t.Fatalf("Encode output incorrect:\n%s", buf.String())
+	}
+
+	var r doc
+	dec := NewDecoder(&buf)
+	dec.Dialect(dl)
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if r.Name != d.Name || r.Notes != d.Notes || string(r.Raw) != string(d.Raw) {
+		t.Fatalf("Quoted round trip incorrect: %q", r)
+	}
+}
+
+func TestMarshalArrayKeys(t *testing.T) {
+	var d struct {
+		Extensions []string       `ini:"extension"`
+		Limits     map[string]int `ini:"limits"`
+	}
+	d.Extensions = []string{"foo.so", "bar.so"}
+	d.Limits = map[string]int{"mem": 512, "cpu": 2}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.ArrayKeys(true)
+	if err := enc.Encode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `extension[]=foo.so
+extension[]=bar.so
+limits[cpu]=2
+limits[mem]=512
+`
+	if buf.String() != expected {
+		t.Fatalf("ArrayKeys output incorrect:\n%s", buf.String())
+	}
+
+	d.Extensions, d.Limits = nil, nil
+	if err := Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	} else if len(d.Extensions) != 2 || d.Limits["mem"] != 512 {
+		t.Fatal("Round trip lost values:", d)
+	}
+}
+
+func TestMarshalSplitList(t *testing.T) {
+	type lists struct {
+		Hosts []string `ini:"Hosts,split=,"`
+		Ports []int    `ini:"Ports,split= "`
+		Names []string `ini:"Names,quoted,split=,"`
+	}
+	d := lists{
+		Hosts: []string{"a.example", "b.example"},
+		Ports: []int{80, 443},
+		Names: []string{"Rock, Roll", "Jazz", ""},
+	}
+
+	b, err := Marshal(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Hosts=a.example, b.example
+Ports=80 443
+Names="Rock, Roll", Jazz, ""
+`
+	if string(b) != expected {
+		t.Fatalf("Split list output incorrect:\n%s", b)
+	}
+
+	var r lists
+	if err := Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	} else if len(r.Names) != 3 || r.Names[0] != "Rock, Roll" || r.Names[2] != "" {
+		t.Fatal("Round trip Names incorrect,", r.Names)
+	} else if len(r.Hosts) != 2 || len(r.Ports) != 2 {
+		t.Fatal("Round trip lost values:", r)
+	}
+
+	d.Hosts = []string{"a,b"}
+	if _, err := Marshal(&d); err == nil {
+		t.Fatal("Expected error for element containing separator")
+	}
+}
+
+func TestMarshalArrays(t *testing.T) {
+	type arrays struct {
+		Gains  [3]float32
+		Origin [2]int `ini:"Origin,split=,"`
+		Curves [][]float64
+		Zones  [][2]int `ini:"Zone,split= "`
+	}
+	d := arrays{
+		Gains:  [3]float32{0.5, 1.5, 2},
+		Origin: [2]int{10, 20},
+		Curves: [][]float64{{0, 0.5, 1}, {0.25}},
+		Zones:  [][2]int{{1, 2}, {3, 4}},
+	}
+
+	b, err := Marshal(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Gains=0.5
+Gains=1.5
+Gains=2
+Origin=10, 20
+Curves=0, 0.5, 1
+Curves=0.25
+Zone=1 2
+Zone=3 4
+`
+	if string(b) != expected {
+		t.Fatalf("Arrays output incorrect:\n%s", b)
+	}
+
+	var r arrays
+	if err := Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	} else if r.Gains != d.Gains || r.Origin != d.Origin || len(r.Curves) != 2 || r.Curves[0][2] != 1 || r.Zones[1] != d.Zones[1] {
+		t.Fatal("Round trip lost values:", r)
+	}
+}
+
+func TestMarshalEmbedded(t *testing.T) {
+	var d struct {
+		embeddedAuth
+		*EmbeddedLimits
+		Name    string
+		secret  string
+		Skipped int `ini:"-"`
+		Conn    struct {
+			embeddedConn
+		} `ini:"[CONN]"`
+	}
+	d.User, d.Pass, d.Name, d.secret = "root", "hunter2", "db", "x"
+	d.Conn.Host = "a.example"
+
+	b, err := Marshal(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `User=root
+password=hunter2
+Name=db
+
+[CONN]
+Host=a.example
+Port=0
+`
+	if string(b) != expected {
+		t.Fatalf("Embedded output incorrect:\n%s", b)
+	}
+}
+
+// Values that look like the start of a heredoc must not be read back as
+// one.
+func TestMarshalHeredocOpener(t *testing.T) {
+	type doc struct {
+		A string
+		B string
+		C string
+	}
+	d := doc{"<<EOT", "<< END", "a <<EOT"}
+
+	heredoc := DefaultDialect
+	heredoc.Continuation = ContinueHeredoc
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Dialect(heredoc)
+	if err := enc.Encode(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	var r doc
+	if err := NewDecoder(bytes.NewReader(buf.Bytes()), WithDialect(heredoc)).Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if r != d {
+		t.Fatalf("Heredoc opener round trip incorrect: %q\n%s", r, buf.String())
+	}
+
+	for _, quoting := range []Quoting{QuoteEscape, QuoteStrip} {
+		dl := heredoc
+		dl.Quoting = quoting
+
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.Dialect(dl)
+		if err := enc.Encode(&d); err != nil {
+			t.Fatal(err)
+		} else if !strings.HasPrefix(buf.String(), "A=\"<<EOT\"\n") {
+			t.Fatalf("Heredoc opener should be quoted:\n%s", buf.String())
+		}
+
+		var r doc
+		dec := NewDecoder(&buf)
+		dec.Dialect(dl)
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		} else if r != d {
+			t.Fatalf("Quoted heredoc opener round trip incorrect: %q", r)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// extendsKey is the key that names the base of a section, like the
+// header [prod : base], when the dialect has Inheritance.
+const extendsKey = "extends"
+
+// A sectionBase is the base a section header declares.
+type sectionBase struct {
+	section string
+	base    string
+	lineNum int
+	line    string
+}
+
+// fieldKey identifies a field of the value being decoded.
+type fieldKey struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+func keyOf(v reflect.Value) (fieldKey, bool) {
+	if !v.CanAddr() {
+		return fieldKey{}, false
+	}
+	return fieldKey{v.UnsafeAddr(), v.Type()}, true
+}
+
+// Forgets what is known about the fields of a new element of a slice.
+// Growing a slice moves its elements, so the new element may sit where
+// an old one was.
+func (d *decodeState) forgetFields(v reflect.Value) {
+	start := v.UnsafeAddr()
+	end := start + v.Type().Size()
+	for _, m := range []map[fieldKey]bool{d.filled, d.overridden, d.inherited} {
+		for k := range m {
+			if k.addr >= start && k.addr < end {
+				delete(m, k)
+			}
+		}
+	}
+	for k := range d.seen {
+		if k.addr >= start && k.addr < end {
+			delete(d.seen, k)
+		}
+	}
+	for k := range d.counts {
+		if k.addr >= start && k.addr < end {
+			delete(d.counts, k)
+		}
+	}
+}
+
+/*
+ * Reads the whole file ahead of decoding, keeping the values of every
+ * section other sections may fall back on, so a DEFAULT or base section
+ * can appear anywhere. Inheritance cycles and unknown bases are reported
+ * on the header that declares them.
+ */
+func (d *decodeState) prescan(data []byte) {
+	p := decodeState{dialect: d.dialect, profile: d.profile, maxLine: d.maxLine}
+	p.scanner = p.newScanner(bytes.NewReader(data))
+
+	var order []int              // header lines declaring a base, in file order
+	seen := make(map[string]int) // first header line of every section
+
+	for p.savedError == nil {
+		ev, ok := p.next()
+		if !ok {
+			break
+		}
+
+		switch {
+		case ev.kind == lineHeader:
+			if _, ok := seen[ev.section.name]; !ok {
+				seen[ev.section.name] = ev.lineNum
+			}
+			if len(ev.section.base) > 0 {
+				order = append(order, ev.lineNum)
+				p.declareBase(ev.section, ev.section.base)
+			}
+
+		case ev.kind != lineKeyValue:
+
+		case p.isExtends(ev.name) && len(ev.section.name) > 0:
+			if _, ok := p.bases[ev.section.lineNum]; !ok {
+				order = append(order, ev.section.lineNum)
+			}
+			p.declareBase(ev.section, strings.TrimSpace(p.dialect.fold(ev.value)))
+
+		default:
+			if p.recorded == nil {
+				p.recorded = make(map[string][]event)
+			}
+			p.recorded[ev.section.name] = append(p.recorded[ev.section.name], ev)
+		}
+	}
+
+	if p.savedError != nil {
+		d.saveError(p.savedError)
+		return
+	}
+
+	d.recorded, d.bases, d.baseOf = p.recorded, p.bases, p.baseOf
+
+	for _, lineNum := range order {
+		decl := d.bases[lineNum]
+		if _, ok := seen[decl.base]; !ok {
+			d.saveError(&IniError{lineNum, decl.line, fmt.Sprintf("Unknown base section %q", decl.base)})
+			return
+		}
+		if _, err := d.baseChain(decl); err != nil {
+			d.saveError(err)
+			return
+		}
+	}
+}
+
+// Declares the base of a section.
+func (d *decodeState) declareBase(section sectionHeader, base string) {
+	if d.bases == nil {
+		d.bases = make(map[int]sectionBase)
+		d.baseOf = make(map[string]sectionBase)
+	}
+
+	decl := sectionBase{section.name, base, section.lineNum, section.line}
+	d.bases[section.lineNum] = decl
+	if _, ok := d.baseOf[section.name]; !ok {
+		d.baseOf[section.name] = decl
+	}
+}
+
+/*
+ * Returns the bases of a section, nearest first, following the first
+ * base declared by each section along the way.
+ */
+func (d *decodeState) baseChain(decl sectionBase) ([]string, error) {
+	first := decl
+	trail := fmt.Sprintf("%s (line %d)", decl.section, decl.lineNum)
+	seen := map[string]bool{decl.section: true}
+
+	var chain []string
+	for len(decl.base) > 0 {
+		next := d.baseOf[decl.base]
+		trail += fmt.Sprintf(" -> %s (line %d)", decl.base, next.lineNum)
+		if seen[decl.base] {
+			return nil, &IniError{first.lineNum, first.line, "Inheritance cycle " + trail}
+		}
+
+		chain = append(chain, decl.base)
+		seen[decl.base] = true
+		decl = next
+	}
+
+	return chain, nil
+}
+
+// Splits a header [name : base] into [name] and base.
+func splitBase(header string) (string, string) {
+	name := header
+	if isBracketed(name) {
+		name = name[1 : len(name)-1]
+	}
+
+	i := strings.IndexByte(name, ':')
+	if i < 0 {
+		return header, ""
+	}
+	return "[" + strings.TrimSpace(name[:i]) + "]", strings.TrimSpace(name[i+1:])
+}
+
+func (d *decodeState) isExtends(key string) bool {
+	return d.dialect.Inheritance && key == d.dialect.fold(extendsKey)
+}
+
+func (d *decodeState) isDefaultSection(section sectionHeader) bool {
+	return len(d.dialect.DefaultSection) > 0 &&
+		section.name == d.dialect.fold(d.dialect.DefaultSection)
+}
+
+// Reports whether a section is DEFAULT or a base of another section,
+// whose keys are used even when no field matches them there.
+func (d *decodeState) isFallbackSection(section sectionHeader) bool {
+	if d.isDefaultSection(section) {
+		return true
+	}
+	for _, decl := range d.baseOf {
+		if decl.base == section.name {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the layers of values a section falls back on, lowest
+// priority first: DEFAULT, then its bases from the farthest.
+func (d *decodeState) fallbacks(section sectionHeader) [][]event {
+	var layers [][]event
+	if len(d.dialect.DefaultSection) > 0 && !d.isDefaultSection(section) {
+		layers = append(layers, d.recorded[d.dialect.fold(d.dialect.DefaultSection)])
+	}
+
+	if decl, ok := d.bases[section.lineNum]; ok {
+		chain, _ := d.baseChain(decl)
+		for i := len(chain) - 1; i >= 0; i-- {
+			layers = append(layers, d.recorded[chain[i]])
+		}
+	}
+
+	return layers
+}
+
+/*
+ * Fills a section that was just entered by the header ev from the
+ * sections it falls back on. Keys of the section itself are set
+ * afterwards and win, and a repeated key of the section replaces an
+ * inherited list instead of appending to it. A struct section is only
+ * filled the first time it is entered.
+ */
+func (d *decodeState) applyFallbacks(prop property, m propertyMap, ev event) {
+	if ev.section.active {
+		return // overrides a section that was already filled
+	}
+
+	if !prop.isArray {
+		k, ok := keyOf(prop.value)
+		if !ok || d.filled[k] {
+			return
+		}
+		if d.filled == nil {
+			d.filled = make(map[fieldKey]bool)
+		}
+		d.filled[k] = true
+	}
+
+	lineNum, line := d.lineNum, d.line
+	d.inFallback = true
+	for _, layer := range d.fallbacks(ev.section) {
+		var lists []fieldKey
+		for _, kv := range layer {
+			p, kv, ok := keyProperty(m.lookup, kv)
+			if !ok || p.isSection {
+				continue
+			}
+
+			// set in the section entered, errors point at the line the
+			// value came from
+			kv.section = ev.section
+			d.lineNum, d.line = kv.lineNum, kv.line
+			d.setProperty(p, kv)
+
+			if k, ok := keyOf(p.value); ok && p.isArray {
+				lists = append(lists, k)
+			}
+		}
+
+		// mark lists only once the whole layer is set, so a layer
+		// appends its own repeated keys
+		for _, k := range lists {
+			if d.inherited == nil {
+				d.inherited = make(map[fieldKey]bool)
+			}
+			d.inherited[k] = true
+		}
+	}
+	d.lineNum, d.line = lineNum, line
+	d.inFallback = false
+}
+
+/*
+ * Turns a git-config style header [section "subsection"] into the
+ * dotted path [section.subsection]. Other headers are unchanged.
+ */
+func subsectionPath(header string) string {
+	name := header
+	if isBracketed(name) {
+		name = name[1 : len(name)-1]
+	}
+
+	i := strings.IndexByte(name, '"')
+	if i < 0 || !strings.HasSuffix(name, `"`) || i == len(name)-1 {
+		return header
+	}
+
+	sub, rest, err := unquotePrefix(name[i:])
+	if err != nil || len(rest) > 0 {
+		return header
+	}
+
+	return "[" + strings.TrimSpace(name[:i]) + "." + sub + "]"
+}
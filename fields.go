@@ -0,0 +1,178 @@
+package ini
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// A field is a struct field the way Unmarshal and Marshal see it,
+// including the fields promoted from embedded structs.
+type field struct {
+	name   string // tag name, or the field name when there is none
+	index  []int  // path of field indexes, see fieldByIndex
+	opts   tagOptions
+	tagged bool
+	depth  int    // number of embedded structs the field is found through
+	doc    string // comment or doc tag, written by Template
+	def    string // default tag, written by Template for a zero key
+}
+
+/*
+ * Returns the fields of a struct type, named by the struct tag tagName,
+ * the way encoding/json finds them.
+ * Unexported fields and fields tagged "-" are left out. The fields of an
+ * embedded struct without a tag name, or of a struct field with the
+ * "inline" option, are promoted into the struct. When several fields
+ * have the same name, the least deeply embedded one wins, then the one
+ * with a tag; if that leaves more than one, none of them is used.
+ */
+func typeFields(t reflect.Type, tagName string) []field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+
+	var fields []field
+	visited := make(map[reflect.Type]bool)
+	next := []embedded{{typ: t}}
+
+	for depth := 0; len(next) > 0; depth++ {
+		current := next
+		next = nil
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue // promoted at a lesser depth already
+			}
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				if sf.PkgPath != "" && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					continue // unexported, only its promoted fields are used
+				}
+
+				name, opts := parseTag(sf.Tag.Get(tagName))
+				name = strings.TrimSpace(name)
+				if name == "-" {
+					continue
+				}
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				if ft.Kind() == reflect.Struct && ((sf.Anonymous && len(name) == 0) || opts.Contains("inline")) {
+					next = append(next, embedded{ft, index})
+					continue
+				} else if sf.PkgPath != "" {
+					continue
+				}
+
+				tagged := len(name) > 0
+				if !tagged {
+					name = sf.Name
+				}
+				doc := sf.Tag.Get("comment")
+				if len(doc) == 0 {
+					doc = sf.Tag.Get("doc")
+				}
+				def := sf.Tag.Get("default")
+				fields = append(fields, field{name, index, opts, tagged, depth, doc, def})
+			}
+		}
+
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+	}
+
+	// keep the dominant field of each name
+	byName := make(map[string][]field)
+	for _, f := range fields {
+		byName[f.name] = append(byName[f.name], f)
+	}
+
+	var out []field
+	for _, f := range fields {
+		if dominant, ok := dominantField(byName[f.name]); ok && sameIndex(dominant.index, f.index) {
+			out = append(out, f)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].index, out[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return out
+}
+
+// Returns the field that wins among fields of the same name, if any.
+func dominantField(fields []field) (field, bool) {
+	var best []field
+	for _, f := range fields {
+		if len(best) == 0 || f.depth < best[0].depth {
+			best = []field{f}
+		} else if f.depth == best[0].depth {
+			best = append(best, f)
+		}
+	}
+
+	if len(best) == 1 {
+		return best[0], true
+	}
+
+	var tagged []field
+	for _, f := range best {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+func sameIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+ * Returns the field of struct v at an index path. Nil pointers to
+ * embedded structs along the way are allocated when alloc is set and
+ * they can be; otherwise the field is reported missing.
+ */
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
@@ -0,0 +1,125 @@
+package ini
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Unmarshaler is implemented by types that decode an INI file
+// themselves, like the UnmarshalINI methods generated by cmd/inigen.
+// Unmarshal calls UnmarshalINI instead of decoding with reflection, as
+// do Decode, DecodeFile and MustLoad without options.
+type Unmarshaler interface {
+	UnmarshalINI(data []byte) error
+}
+
+// Marshaler is implemented by types that encode themselves as an INI
+// file, like the MarshalINI methods generated by cmd/inigen. Marshal
+// calls MarshalINI instead of encoding with reflection.
+type Marshaler interface {
+	MarshalINI() ([]byte, error)
+}
+
+// The functions below are used by code generated by cmd/inigen, and
+// read values the way Unmarshal does in DefaultDialect.
+
+// SectionName returns the name of a header token in the form section
+// tags are matched in.
+func SectionName(tok Token) string {
+	return DefaultDialect.fold(tok.Name)
+}
+
+// KeyName returns the name of a key token in the form tags are matched
+// in, as written.
+func KeyName(tok Token) string {
+	return DefaultDialect.fold(tok.Name)
+}
+
+// ListName returns the name of a key token in the form tags are matched
+// in, without a [] or [name] suffix, which names the slice it sets.
+func ListName(tok Token) string {
+	name, _ := splitIndex(tok.Name)
+	return DefaultDialect.fold(name)
+}
+
+// ParseBool returns the value of a token as a bool: true for t, true,
+// y, yes, on and 1 in any case, false otherwise.
+func ParseBool(tok Token) bool {
+	return boolValue(tok.Value)
+}
+
+// ParseInt returns the value of a token as an integer of the given bit
+// size, where 0 is the size of int.
+func ParseInt(tok Token, bits int) (int64, error) {
+	n, err := strconv.ParseInt(tok.Value, 10, bits)
+	if err != nil {
+		return 0, &IniError{tok.Line, tok.Raw, "Invalid int"}
+	}
+	return n, nil
+}
+
+// ParseUint returns the value of a token as an unsigned integer of the
+// given bit size, where 0 is the size of uint.
+func ParseUint(tok Token, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(tok.Value, 10, bits)
+	if err != nil {
+		return 0, &IniError{tok.Line, tok.Raw, "Invalid uint"}
+	}
+	return n, nil
+}
+
+// ParseFloat returns the value of a token as a float of the given bit
+// size, 32 or 64.
+func ParseFloat(tok Token, bits int) (float64, error) {
+	n, err := strconv.ParseFloat(tok.Value, bits)
+	if err != nil {
+		return 0, &IniError{tok.Line, tok.Raw, "Invalid float"}
+	}
+	return n, nil
+}
+
+// AppendZero appends the zero value of T to *s, starting a new section
+// of a slice of structs.
+func AppendZero[T any](s *[]T) {
+	var zero T
+	*s = append(*s, zero)
+}
+
+// Last returns the last element of s, the section being decoded.
+func Last[T any](s []T) *T {
+	return &s[len(s)-1]
+}
+
+// A Writer writes an INI file the way Marshal writes it in
+// DefaultDialect.
+type Writer struct {
+	e encodeState
+}
+
+// Key writes a NAME=VALUE line. A value that would not read back the
+// same, such as one holding newlines, is an error reported by Err.
+func (w *Writer) Key(name, value string) {
+	if w.e.dialect.Delimiters == "" {
+		w.e.dialect = DefaultDialect
+	}
+	w.e.writeValue(name, reflect.ValueOf(value))
+}
+
+// Section writes the header of a section, after a blank line unless
+// it is the first line.
+func (w *Writer) Section(name string) {
+	if w.e.Len() > 0 {
+		w.e.WriteByte('\n')
+	}
+	w.e.WriteString("[" + name + "]\n")
+}
+
+// Bytes returns the file written so far.
+func (w *Writer) Bytes() []byte {
+	return w.e.Bytes()
+}
+
+// Err returns the first error of the keys written.
+func (w *Writer) Err() error {
+	return w.e.err
+}
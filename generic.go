@@ -0,0 +1,92 @@
+package ini
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+)
+
+// Decode returns the value of type T decoded from an INI file, the way
+// Unmarshal decodes it. T is usually a struct, a pointer to a struct, or
+// map[string]interface{}. Without options, a T implementing
+// Unmarshaler, like the types cmd/inigen writes methods for, decodes
+// itself with UnmarshalINI, as with Unmarshal.
+func Decode[T any](data []byte, opts ...Option) (T, error) {
+	return decodeFrom[T](bytes.NewReader(data), opts)
+}
+
+// DecodeFile returns the value of type T decoded from the INI file at
+// path, like Decode.
+func DecodeFile[T any](path string, opts ...Option) (T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer f.Close()
+
+	return decodeFrom[T](f, opts)
+}
+
+// MustLoad is like DecodeFile but panics if the file can't be read or
+// decoded. It simplifies loading the configuration of a program that
+// can't run without it.
+func MustLoad[T any](path string, opts ...Option) T {
+	v, err := DecodeFile[T](path, opts...)
+	if err != nil {
+		panic(fmt.Errorf("ini: %s: %w", path, err))
+	}
+	return v
+}
+
+/*
+ * Section returns the section [name] of an INI file decoded into a value
+ * of type T, ignoring the rest of the file. T may be a slice of structs
+ * to read every section of that name.
+ */
+func Section[T any](data []byte, name string, opts ...Option) (T, error) {
+	dec := NewDecoder(bytes.NewReader(data), opts...)
+	v := reflect.New(sectionOf(reflect.TypeOf((*T)(nil)).Elem(), name, dec.d.tag()))
+	err := dec.Decode(v.Interface())
+
+	return v.Elem().Field(0).Interface().(T), err
+}
+
+// Returns a struct type with a single field of type t, for the section
+// [name], named by the struct tag tagName.
+func sectionOf(t reflect.Type, name, tagName string) reflect.Type {
+	if !isBracketed(name) {
+		name = "[" + name + "]"
+	}
+
+	return reflect.StructOf([]reflect.StructField{{
+		Name: "Section",
+		Type: t,
+		Tag:  reflect.StructTag(tagName + ":" + strconv.Quote(name)),
+	}})
+}
+
+// Decodes a value of type T from r, with UnmarshalINI when T or *T
+// implements Unmarshaler and there are no options.
+func decodeFrom[T any](r io.Reader, opts []Option) (T, error) {
+	var v T
+	target := interface{}(&v)
+	if rv := reflect.ValueOf(&v).Elem(); rv.Kind() == reflect.Ptr {
+		rv.Set(reflect.New(rv.Type().Elem()))
+		target = v
+	}
+
+	if u, ok := target.(Unmarshaler); ok && len(opts) == 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return v, err
+		}
+		return v, u.UnmarshalINI(data)
+	}
+
+	err := NewDecoder(r, opts...).Decode(target)
+	return v, err
+}
@@ -0,0 +1,149 @@
+package ini
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type genericConfig struct {
+	Name  string
+	MySQL struct {
+		Host string
+		Port int
+	} `ini:"[MYSQL]"`
+}
+
+var genericINI = []byte(`
+Name=tunes
+[MYSQL]
+Host=localhost
+Port=3306
+[MYSQL@prod]
+Host=db.example.com
+`)
+
+func TestDecodeGeneric(t *testing.T) {
+	c, err := Decode[genericConfig](genericINI)
+	if err != nil {
+		t.Fatal(err)
+	} else if c.Name != "tunes" || c.MySQL.Port != 3306 {
+		t.Fatal("Decode incorrect,", c)
+	}
+
+	prod := func(dec *Decoder) { dec.Profile("prod") }
+	p, err := Decode[*genericConfig](genericINI, prod)
+	if err != nil {
+		t.Fatal(err)
+	} else if p == nil || p.MySQL.Host != "db.example.com" {
+		t.Fatal("Decode into pointer with option incorrect,", p)
+	}
+
+	m, err := Decode[map[string]interface{}](genericINI)
+	if err != nil {
+		t.Fatal(err)
+	} else if m["name"] != "tunes" {
+		t.Fatal("Decode into map incorrect,", m)
+	}
+
+	if _, err := Decode[genericConfig]([]byte("[MYSQL]\nPort=x")); err == nil {
+		t.Fatal("Expected error for invalid port")
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.ini")
+	if err := os.WriteFile(path, genericINI, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := DecodeFile[genericConfig](path)
+	if err != nil {
+		t.Fatal(err)
+	} else if c.MySQL.Host != "localhost" {
+		t.Fatal("DecodeFile incorrect,", c)
+	}
+
+	if c := MustLoad[genericConfig](path); c.Name != "tunes" {
+		t.Fatal("MustLoad incorrect,", c)
+	}
+
+	if _, err := DecodeFile[genericConfig](path + ".missing"); err == nil {
+		t.Fatal("Expected error for missing file")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustLoad should panic for a missing file")
+		}
+	}()
+	MustLoad[genericConfig](path + ".missing")
+}
+
+func TestSection(t *testing.T) {
+	type mysql struct {
+		Host string
+		Port int
+	}
+
+	s, err := Section[mysql](genericINI, "MYSQL")
+	if err != nil {
+		t.Fatal(err)
+	} else if s.Host != "localhost" || s.Port != 3306 {
+		t.Fatal("Section incorrect,", s)
+	}
+
+	songs, err := Section[[]struct{ Title string }]([]byte("[SONG]\nTitle=a\n[SONG]\nTitle=b"), "[SONG]")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 2 || songs[1].Title != "b" {
+		t.Fatal("Repeated section incorrect,", songs)
+	}
+
+	type cfgMysql struct {
+		Server string `cfg:"Host"`
+	}
+	c, err := Section[cfgMysql](genericINI, "MYSQL", WithTagName("cfg"))
+	if err != nil {
+		t.Fatal(err)
+	} else if c.Server != "localhost" {
+		t.Fatal("Section with another tag name incorrect,", c)
+	}
+}
+
+// selfDecoding implements Unmarshaler, counting its calls.
+type selfDecoding struct {
+	Name  string
+	calls int
+}
+
+func (s *selfDecoding) UnmarshalINI(data []byte) error {
+	s.calls++
+	s.Name = "self"
+	return nil
+}
+
+func TestDecodeGenericUnmarshaler(t *testing.T) {
+	c, err := Decode[selfDecoding](genericINI)
+	if err != nil || c.calls != 1 || c.Name != "self" {
+		t.Fatal("Expected UnmarshalINI to decode,", c, err)
+	}
+
+	p, err := Decode[*selfDecoding](genericINI)
+	if err != nil || p.calls != 1 {
+		t.Fatal("Expected UnmarshalINI to decode a pointer,", p, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.ini")
+	if err := os.WriteFile(path, genericINI, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c := MustLoad[selfDecoding](path); c.calls != 1 {
+		t.Fatal("Expected MustLoad to use UnmarshalINI,", c)
+	}
+
+	c, err = Decode[selfDecoding](genericINI, WithStrict())
+	if err == nil || c.calls != 0 {
+		t.Fatal("Expected options to decode with reflection,", c, err)
+	}
+}
@@ -0,0 +1,241 @@
+package ini
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+ * InferStruct reads a sample INI file from r and returns the Go source
+ * of a struct type named name that decodes it, as a starting point for
+ * a hand written type. Sections become struct fields tagged with their
+ * header, and a section appearing more than once a slice of structs. A
+ * key set more than once in a section, or written like extension[],
+ * becomes a slice, and one like limits[cpu] a map. Values that all read
+ * as integers, floats or booleans (true, yes, on, false, no, off) give
+ * int, float64 or bool fields, others strings. Names become exported
+ * Go identifiers, with a tag when they wouldn't match the key.
+ *
+ * Of the options, those of NewScanner apply. Every section is placed at
+ * the top level, since a sample can't tell which are nested, except for
+ * the subsections of a dialect with Subsections: [remote "origin"] is a
+ * section Origin inside Remote, the path the header is decoded as.
+ */
+func InferStruct(r io.Reader, name string, opts ...Option) ([]byte, error) {
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("Invalid type name %q", name)
+	}
+
+	s := NewScanner(r, opts...)
+	dl := &s.d.dialect
+	root := newInferStruct()
+	cur := root
+	for {
+		tok, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok.Kind {
+		case TokenSectionHeader:
+			cur = root
+			for _, name := range sectionPath(dl, tok.Name) {
+				f := cur.field("["+dl.fold(name)+"]", name)
+				if f.section == nil {
+					f.section = newInferStruct()
+					f.section.parent = f
+				}
+				cur = f.section
+			}
+			if cur.entered {
+				cur.parent.list = true
+			}
+			cur.entered = true
+			cur.counts = make(map[string]int)
+		case TokenKeyValue:
+			cur.key(dl, tok)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("type " + name + " ")
+	root.write(&b, dl)
+	b.WriteString("\n")
+	return format.Source([]byte(b.String()))
+}
+
+// An inferStruct holds the keys of a section, or the keys and sections
+// of the top level, in the order they first appear.
+type inferStruct struct {
+	fields  []*inferField
+	byName  map[string]*inferField // by folded name, [name] for sections
+	counts  map[string]int         // keys set in the section being read
+	parent  *inferField            // field of a section
+	entered bool                   // a header entered the section
+}
+
+type inferField struct {
+	name    string       // as first written, without an index
+	kind    reflect.Kind // Invalid until a value is read
+	list    bool
+	isMap   bool
+	section *inferStruct
+}
+
+func newInferStruct() *inferStruct {
+	return &inferStruct{byName: make(map[string]*inferField), counts: make(map[string]int)}
+}
+
+// Returns the field of a folded name, adding it when it's new.
+func (s *inferStruct) field(folded, name string) *inferField {
+	f, ok := s.byName[folded]
+	if !ok {
+		f = &inferField{name: name}
+		s.byName[folded] = f
+		s.fields = append(s.fields, f)
+	}
+	return f
+}
+
+// Returns the names of the sections a header enters: the header, or
+// with Subsections the path of a header like [remote "origin"], split
+// the way the decoder splits it.
+func sectionPath(dl *Dialect, header string) []string {
+	if dl.Subsections {
+		if path := subsectionPath("[" + header + "]"); path != "["+header+"]" {
+			names := strings.Split(path[1:len(path)-1], ".")
+			for i := range names {
+				names[i] = strings.TrimSpace(names[i])
+			}
+			return names
+		}
+	}
+	return []string{header}
+}
+
+// Adds a key token to the section, widening the type of its field to
+// fit the value.
+func (s *inferStruct) key(dl *Dialect, tok Token) {
+	name, index := splitIndex(tok.Name)
+	folded := dl.fold(name)
+	f := s.field(folded, name)
+
+	s.counts[folded]++
+	if len(index) > 0 {
+		f.isMap = true
+	} else if name != tok.Name || s.counts[folded] > 1 {
+		f.list = true
+	}
+	f.kind = widenKind(f.kind, valueKind(tok.Value))
+}
+
+// Returns the kind of field a value reads as, Invalid when it's empty
+// and fits any.
+func valueKind(s string) reflect.Kind {
+	if len(s) == 0 {
+		return reflect.Invalid
+	}
+
+	switch inferValue(s).(type) {
+	case bool:
+		return reflect.Bool
+	case int64:
+		return reflect.Int
+	case float64:
+		return reflect.Float64
+	}
+	return reflect.String
+}
+
+// Returns the kind of field holding values of both kinds.
+func widenKind(a, b reflect.Kind) reflect.Kind {
+	switch {
+	case a == reflect.Invalid || a == b:
+		return b
+	case b == reflect.Invalid:
+		return a
+	case (a == reflect.Int || a == reflect.Float64) && (b == reflect.Int || b == reflect.Float64):
+		return reflect.Float64
+	}
+	return reflect.String
+}
+
+func (s *inferStruct) write(b *strings.Builder, dl *Dialect) {
+	b.WriteString("struct {\n")
+	used := make(map[string]bool)
+	for _, f := range s.fields {
+		name := uniqueName(goName(f.name), used)
+		b.WriteString(name + " ")
+
+		tag := f.name
+		if f.list {
+			b.WriteString("[]")
+		} else if f.isMap {
+			b.WriteString("map[string]")
+		}
+
+		if f.section != nil {
+			f.section.write(b, dl)
+			tag = "[" + f.name + "]"
+		} else {
+			kind := f.kind
+			if kind == reflect.Invalid {
+				kind = reflect.String
+			}
+			b.WriteString(kind.String())
+			if dl.fold(name) == dl.fold(f.name) {
+				tag = ""
+			}
+		}
+
+		if len(tag) > 0 {
+			b.WriteString(" `ini:" + strconv.Quote(tag) + "`")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+}
+
+// Returns an exported Go identifier for an INI name: CREATE SONG
+// becomes CreateSong, song_id SongId and 2fa X2fa.
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		if strings.ToUpper(w) == w {
+			w = strings.ToLower(w)
+		}
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	name := b.String()
+	if len(name) == 0 {
+		return "Field"
+	} else if !unicode.IsLetter([]rune(name)[0]) {
+		return "X" + name
+	}
+	return name
+}
+
+// Returns name, or name with a number appended when it's used already.
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
@@ -0,0 +1,167 @@
+package ini
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The type InferStruct writes for testdata/infer/tunes.ini.
+type inferredPlayer struct {
+	Name       string
+	Shuffle    bool
+	CreateSong []struct {
+		SongId int
+		Title  string
+		Artist string
+		Rating float64
+	} `ini:"[CREATE SONG]"`
+	CreatePlaylist struct {
+		PlaylistId int
+		Title      string
+		Song       []int
+	} `ini:"[CREATE PLAYLIST]"`
+	Network struct {
+		Address string
+		Dns     []string
+		Limits  map[string]float64
+		X2fa    string `ini:"2fa"`
+	} `ini:"[network]"`
+}
+
+func TestInferStruct(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "infer", "tunes.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := InferStruct(bytes.NewReader(data), "TunePlayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "infer", "tunes.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(src, want) {
+		t.Fatalf("Inferred\n%s\nwant\n%s", src, want)
+	}
+
+	// inferredPlayer is the golden type, which should decode the sample
+	var p inferredPlayer
+	if err := Unmarshal(data, &p); err != nil {
+		t.Fatal(err)
+	} else if len(p.CreateSong) != 2 || p.CreateSong[1].Rating != 4.5 || !reflect.DeepEqual(p.CreatePlaylist.Song, []int{21348, 9855}) {
+		t.Fatal("Inferred struct decoded incorrectly,", p)
+	} else if !p.Shuffle || len(p.Network.Dns) != 1 || p.Network.Limits["max"] != 60.5 {
+		t.Fatal("Inferred struct decoded incorrectly,", p)
+	}
+
+	src, err = InferStruct(strings.NewReader("shuffle=on\n"), "Player", WithCaseSensitiveKeys())
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(src), "Shuffle bool `ini:\"shuffle\"`") {
+		t.Fatal("Expected a tag for a case sensitive key,", string(src))
+	}
+
+	_, err = InferStruct(bytes.NewReader(data), "tune player")
+	if err == nil || err.Error() != `Invalid type name "tune player"` {
+		t.Fatal("Expected invalid type name error,", err)
+	}
+}
+
+func TestInferStructDialects(t *testing.T) {
+	samples := []struct {
+		dialect Dialect
+		file    string
+	}{
+		{DefaultDialect, "infer/tunes.ini"},
+		{PHPDialect, "dialects/php/php.ini"},
+		{PythonDialect, "dialects/python/configparser.ini"},
+		{SystemdDialect, "dialects/systemd/unit.ini"},
+		{WindowsDialect, "dialects/windows/win.ini"},
+		{GitDialect, "dialects/git/config.ini"},
+	}
+
+	for _, sample := range samples {
+		data, err := os.ReadFile(filepath.Join("testdata", sample.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src, err := InferStruct(bytes.NewReader(data), "Config", WithDialect(sample.dialect))
+		if err != nil {
+			t.Fatal(sample.file, err)
+		}
+
+		v := reflect.New(sourceType(t, src))
+		dec := NewDecoder(bytes.NewReader(data), WithDialect(sample.dialect))
+		if err := dec.Decode(v.Interface()); err != nil {
+			t.Fatal(sample.file, err)
+		} else if len(dec.Unmatched()) > 0 {
+			t.Fatalf("%s: Unmatched lines %v decoding into\n%s", sample.file, dec.Unmatched(), src)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join("testdata", "dialects", "git", "config.ini"))
+	src, _ := InferStruct(bytes.NewReader(data), "Config", WithDialect(GitDialect))
+	if !strings.Contains(string(src), "Origin struct {") {
+		t.Fatal("Expected a nested struct for a subsection,", string(src))
+	}
+}
+
+// Returns the type of the struct source InferStruct writes, built with
+// reflect.
+func sourceType(t *testing.T, src []byte) reflect.Type {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+string(src), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	return exprType(t, spec.Type)
+}
+
+func exprType(t *testing.T, expr ast.Expr) reflect.Type {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		types := map[string]reflect.Type{
+			"string":  reflect.TypeOf(""),
+			"int":     reflect.TypeOf(0),
+			"float64": reflect.TypeOf(0.0),
+			"bool":    reflect.TypeOf(false),
+		}
+		if typ, ok := types[e.Name]; ok {
+			return typ
+		}
+	case *ast.ArrayType:
+		return reflect.SliceOf(exprType(t, e.Elt))
+	case *ast.MapType:
+		return reflect.MapOf(exprType(t, e.Key), exprType(t, e.Value))
+	case *ast.StructType:
+		var fields []reflect.StructField
+		for _, field := range e.Fields.List {
+			sf := reflect.StructField{Name: field.Names[0].Name, Type: exprType(t, field.Type)}
+			if field.Tag != nil {
+				tag, _ := strconv.Unquote(field.Tag.Value)
+				sf.Tag = reflect.StructTag(tag)
+			}
+			fields = append(fields, sf)
+		}
+		return reflect.StructOf(fields)
+	}
+	t.Fatalf("Unexpected type %T in inferred source", expr)
+	return nil
+}
@@ -2,6 +2,9 @@ package ini
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -60,7 +63,7 @@ Magic=42`)
 	} else if d.Start.Magic != 42 {
 		t.Fatal("Magic not set")
 	} else if len(unmatched) != 1 {
-		t.Fatal("Wrong number of unmatched lines (%d): %v", len(unmatched), unmatched)
+		t.Fatalf("Wrong number of unmatched lines (%d): %v", len(unmatched), unmatched)
 	} else if unmatched[0].line != "UNMATCHED=ME" {
 		t.Fatal("Unmatched line does not match")
 	}
@@ -175,7 +178,7 @@ func TestDeep(t *testing.T) {
 			MaxSpeed      int    `ini:"SET OPTION CONTENT DOWNLOAD MAX KBS"`
 			DownloadStart string `ini:"SET OPTION NETWORK DOWNLOAD WINDOW START"`
 			DownloadEnd   string `ini:"SET OPTION NETWORK DOWNLOAD WINDOW END"`
-		} `ini:"-"`
+		} `ini:",inline"`
 	}
 	b := []byte(`
 SET OPTION SECURE AUTH PORT=8080
@@ -387,7 +390,6 @@ Add Song=false
 	}
 }
 
-/*
 func TestArrayStruct(t *testing.T) {
 	var d struct {
 		Device struct {
@@ -465,7 +467,6 @@ SET DEFAULT CHANNEL=19
 		t.Fatal("Zones[1] Channel is incorrect")
 	}
 }
-*/
 
 func TestStructsInStructs(t *testing.T) {
 	var d struct {
@@ -508,3 +509,790 @@ BitRate=128
 		t.Fatal("Incorrect bitrate for source[1],", d.Tracks[0].Sources[1].BitRate)
 	}
 }
+
+func TestDottedSections(t *testing.T) {
+	var d struct {
+		Server struct {
+			Host string
+			TLS  struct {
+				Cert   string
+				Client struct {
+					Verify bool
+				} `ini:"[client]"`
+			} `ini:"[tls]"`
+		} `ini:"[server],dotted"`
+	}
+
+	b := []byte(`
+[server.tls.client]
+verify=yes
+
+[server]
+host=example.com
+
+[server.tls]
+cert=/etc/ssl/server.pem
+`)
+
+	err := Unmarshal(b, &d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Server.Host != "example.com" {
+		t.Fatal("Server Host not set")
+	} else if d.Server.TLS.Cert != "/etc/ssl/server.pem" {
+		t.Fatal("Server TLS Cert not set")
+	} else if d.Server.TLS.Client.Verify != true {
+		t.Fatal("Server TLS Client Verify not set")
+	}
+}
+
+func TestDottedSectionsDecoder(t *testing.T) {
+	var d struct {
+		Tracks []struct {
+			Title   string
+			Sources []struct {
+				BitRate int
+			} `ini:"[source]"`
+		} `ini:"[track]"`
+	}
+
+	b := []byte(`
+[track]
+title=One
+[track.source]
+bitrate=64
+[track]
+title=Two
+[track.source]
+bitrate=128
+[track.source]
+bitrate=256
+`)
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.DottedSections()
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Tracks) != 2 {
+		t.Fatal("Incorrect number of tracks,", len(d.Tracks))
+	} else if len(d.Tracks[0].Sources) != 1 || d.Tracks[0].Sources[0].BitRate != 64 {
+		t.Fatal("Tracks[0] sources are incorrect,", d.Tracks[0].Sources)
+	} else if len(d.Tracks[1].Sources) != 2 || d.Tracks[1].Sources[1].BitRate != 256 {
+		t.Fatal("Tracks[1] sources are incorrect,", d.Tracks[1].Sources)
+	} else if len(dec.Unmatched()) != 0 {
+		t.Fatal("Unexpected unmatched lines,", dec.Unmatched())
+	}
+}
+
+// countingReader counts the bytes read from it, and fails with err once
+// r is done, if set.
+type countingReader struct {
+	r   io.Reader
+	n   int
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	if err == io.EOF && c.err != nil {
+		err = c.err
+	}
+	return n, err
+}
+
+func TestDecoderStream(t *testing.T) {
+	var d struct {
+		Songs []struct {
+			Title string
+			Art   string
+		} `ini:"[song]"`
+	}
+
+	art := strings.Repeat("QUJD", 50000)
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString("[song]\nTitle=Song\nArt=" + art + "\n")
+	}
+
+	r := &countingReader{r: strings.NewReader(b.String())}
+	read := 0
+	tracer := TracerFunc(func(ev TraceEvent) {
+		if ev.Line == 3 && read == 0 {
+			read = r.n
+		}
+	})
+
+	err := NewDecoder(r, WithMaxLineLength(1<<20), WithTracer(tracer)).Decode(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Songs) != 100 || d.Songs[99].Art != art {
+		t.Fatal("Long lines not decoded,", len(d.Songs))
+	} else if read == 0 || read >= b.Len()/10 {
+		t.Fatal("Reader not read a line at a time,", read, "of", b.Len())
+	}
+
+	err = NewDecoder(strings.NewReader(b.String())).Decode(&d)
+	if err == nil || err.Error() != `Line longer than 65535 bytes on line 3: ""` {
+		t.Fatal("Expected line too long error,", err)
+	}
+
+	r = &countingReader{r: strings.NewReader("[song]\nTitle=One\n"), err: io.ErrUnexpectedEOF}
+	err = NewDecoder(r).Decode(&d)
+	var iniErr *IniError
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !errors.As(err, &iniErr) {
+		t.Fatal("Expected reader error,", err)
+	} else if err.Error() != `unexpected EOF on line 3: ""` {
+		t.Fatal("Reader error on wrong line,", err)
+	}
+}
+
+func TestContinuation(t *testing.T) {
+	var d struct {
+		Query struct {
+			Sql   string
+			Notes string
+			Cert  string
+		} `ini:"[QUERY]"`
+	}
+
+	b := []byte(`
+[QUERY]
+SQL=SELECT id, title \
+FROM songs
+NOTES=first line
+  second line
+  third line
+CERT <<EOT
+-----BEGIN CERTIFICATE-----
+  MIIB
+-----END CERTIFICATE-----
+EOT
+`)
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dl := DefaultDialect
+	dl.Continuation = ContinueHeredoc | ContinueBackslash | ContinueIndent
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Query.Sql != "SELECT id, title FROM songs" {
+		t.Fatalf("Backslash continuation incorrect: %q", d.Query.Sql)
+	} else if d.Query.Notes != "first line\nsecond line\nthird line" {
+		t.Fatalf("Indented continuation incorrect: %q", d.Query.Notes)
+	} else if d.Query.Cert != "-----BEGIN CERTIFICATE-----\n  MIIB\n-----END CERTIFICATE-----" {
+		t.Fatalf("Heredoc incorrect: %q", d.Query.Cert)
+	}
+}
+
+func TestContinuationErrorLine(t *testing.T) {
+	var d struct {
+		Count int
+		Text  string
+	}
+
+	b := []byte(`
+COUNT=1\
+2x
+TEXT=<<END
+never ends
+`)
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dl := DefaultDialect
+	dl.Continuation = ContinueHeredoc | ContinueBackslash
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if e, ok := err.(*IniError); !ok || e.lineNum != 2 {
+		t.Fatal("Expected error on line 2, got", err)
+	}
+
+	dec = NewDecoder(bytes.NewReader([]byte("\n\nTEXT=<<END\nnever ends\n")), WithDialect(dl))
+	err = dec.Decode(&d)
+	if e, ok := err.(*IniError); !ok || e.lineNum != 3 {
+		t.Fatal("Expected error on line 3, got", err)
+	}
+}
+
+func TestQuotedValues(t *testing.T) {
+	var d struct {
+		Playlist struct {
+			Name    string
+			Padded  string
+			Escaped string
+			Single  string
+			Plain   string
+			Raw     RawValue
+			Songs   []string `ini:"Add Song"`
+		} `ini:"[CREATE PLAYLIST]"`
+	}
+
+	b := []byte(`
+[CREATE PLAYLIST] ; the only one
+Name=Rock & Roll ; live
+Padded="  spaced  "   # keep the spaces
+Escaped="tab\there \"quoted\" \u00e9\\"
+Single='C:\new; not a comment'
+Plain=http://example.com/#top
+Raw="  raw " ; comment
+Add Song="Time to Run"
+Add Song=W H O K I L L
+`)
+
+	dl := DefaultDialect
+	dl.Quoting = QuoteEscape
+	dl.InlineComments = true
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Dialect(dl)
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := d.Playlist
+	if p.Name != "Rock & Roll" {
+		t.Fatalf("Name incorrect: %q", p.Name)
+	} else if p.Padded != "  spaced  " {
+		t.Fatalf("Padded incorrect: %q", p.Padded)
+	} else if p.Escaped != "tab\there \"quoted\" \u00e9\\" {
+		t.Fatalf("Escaped incorrect: %q", p.Escaped)
+	} else if p.Single != `C:\new; not a comment` {
+		t.Fatalf("Single incorrect: %q", p.Single)
+	} else if p.Plain != "http://example.com/#top" {
+		t.Fatalf("Plain incorrect: %q", p.Plain)
+	} else if string(p.Raw) != `"  raw " ; comment` {
+		t.Fatalf("Raw incorrect: %q", p.Raw)
+	} else if len(p.Songs) != 2 || p.Songs[0] != "Time to Run" {
+		t.Fatalf("Songs incorrect: %q", p.Songs)
+	}
+
+	// without quoting, values are read as before
+	b = []byte(`
+[CREATE PLAYLIST]
+Name=Rock & Roll ; live
+Padded="  spaced  "
+`)
+	if err := Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	} else if d.Playlist.Name != "Rock & Roll ; live" {
+		t.Fatalf("Name incorrect: %q", d.Playlist.Name)
+	} else if d.Playlist.Padded != `"  spaced  "` {
+		t.Fatalf("Padded incorrect: %q", d.Playlist.Padded)
+	}
+}
+
+func TestQuotedValueErrors(t *testing.T) {
+	var d struct {
+		Name string
+	}
+
+	dl := DefaultDialect
+	dl.Quoting = QuoteEscape
+
+	for _, b := range []string{
+		"\nNAME=\"unterminated\n",
+		"\nNAME=\"bad \\q escape\"\n",
+		"\nNAME=\"trailing\" text\n",
+	} {
+		dec := NewDecoder(bytes.NewReader([]byte(b)))
+		dec.Dialect(dl)
+		err := dec.Decode(&d)
+		if e, ok := err.(*IniError); !ok || e.lineNum != 2 {
+			t.Fatalf("Expected error on line 2 for %q, got %v", b, err)
+		}
+	}
+}
+
+func TestArrayKeys(t *testing.T) {
+	var d struct {
+		Extensions []string       `ini:"extension"`
+		Limits     map[string]int `ini:"limits"`
+		Server     struct {
+			Labels map[string]string
+		} `ini:"[SERVER]"`
+	}
+
+	b := []byte(`
+extension[]=foo.so
+extension[] = bar.so
+extension=baz.so
+limits[cpu]=2
+limits["Memory"]=512
+
+[SERVER]
+labels[Env]=prod
+labels[tier]=web
+`)
+
+	err := Unmarshal(b, &d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Extensions) != 3 || d.Extensions[1] != "bar.so" || d.Extensions[2] != "baz.so" {
+		t.Fatal("Extensions incorrect,", d.Extensions)
+	} else if len(d.Limits) != 2 || d.Limits["cpu"] != 2 || d.Limits["Memory"] != 512 {
+		t.Fatal("Limits incorrect,", d.Limits)
+	} else if len(d.Server.Labels) != 2 || d.Server.Labels["Env"] != "prod" {
+		t.Fatal("Server Labels incorrect,", d.Server.Labels)
+	}
+
+	errs := []string{
+		"limits=2",
+		"limits[]=2",
+		"limits[cpu]=two",
+	}
+	for _, s := range errs {
+		if err := Unmarshal([]byte(s), &d); err == nil {
+			t.Fatalf("Expected error for %q", s)
+		}
+	}
+
+	var scalars struct {
+		Limits int
+		Name   string
+		Opt    string `ini:"opt[1]"`
+	}
+	dec := NewDecoder(bytes.NewReader([]byte("limits[cpu]=2\nname[]=x\nopt[1]=v\n")))
+	if err := dec.Decode(&scalars); err != nil {
+		t.Fatal(err)
+	}
+	if scalars.Limits != 0 || scalars.Name != "" || scalars.Opt != "v" {
+		t.Fatal("Bracketed keys set scalar fields,", scalars)
+	} else if u := dec.Unmatched(); len(u) != 2 || u[0].lineNum != 1 || u[1].lineNum != 2 {
+		t.Fatal("Unmatched lines incorrect,", u)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	var d struct {
+		Hosts  []string `ini:"Hosts,split=,"`
+		Ports  []int    `ini:"Ports,split= "`
+		Names  []string `ini:"Names,quoted,split=;"`
+		Weight []float64
+	}
+
+	b := []byte(`
+Hosts=a.example, b.example ,c.example
+Hosts=d.example
+Hosts=e.example,f.example
+Ports=80  443
+Names="Rock; Roll" ; 'Jazz' ;Pop
+Weight=1.5
+`)
+
+	err := Unmarshal(b, &d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Hosts) != 6 || d.Hosts[1] != "b.example" || d.Hosts[3] != "d.example" || d.Hosts[5] != "f.example" {
+		t.Fatal("Hosts incorrect,", d.Hosts)
+	} else if len(d.Ports) != 2 || d.Ports[1] != 443 {
+		t.Fatal("Ports incorrect,", d.Ports)
+	} else if len(d.Names) != 3 || d.Names[0] != "Rock; Roll" || d.Names[1] != "Jazz" || d.Names[2] != "Pop" {
+		t.Fatal("Names incorrect,", d.Names)
+	} else if len(d.Weight) != 1 || d.Weight[0] != 1.5 {
+		t.Fatal("Weight incorrect,", d.Weight)
+	}
+
+	errs := []string{
+		"Ports=80 http",
+		`Names="Rock" Roll`,
+		`Names="Rock`,
+	}
+	for _, s := range errs {
+		if err := Unmarshal([]byte(s), &d); err == nil {
+			t.Fatalf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	type config struct {
+		Mysql struct {
+			Host string
+			Port int    `ini:"Port,dup=first"`
+			User string `ini:"User,dup=error"`
+		} `ini:"[MYSQL]"`
+		Songs []struct {
+			Title string
+		} `ini:"[SONG]"`
+	}
+
+	b := []byte(`
+[MYSQL]
+Host=a.example
+Port=3306
+User=root
+[SONG]
+Title=Long Way to Go
+[SONG]
+Title=The Falcon Lead
+[MYSQL]
+Host=b.example
+Port=3307
+`)
+
+	var d config
+	if err := Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	} else if d.Mysql.Host != "b.example" || d.Mysql.Port != 3306 {
+		t.Fatal("Default and tag policies incorrect,", d.Mysql)
+	} else if len(d.Songs) != 2 {
+		t.Fatal("Repeated sections are not duplicates,", d.Songs)
+	}
+
+	var dups []Duplicate
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Duplicates(DuplicateWarn)
+	dec.OnDuplicate(func(dup Duplicate) {
+		dups = append(dups, dup)
+	})
+	d = config{}
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	} else if len(dups) != 2 || dups[0].Section != "mysql" || dups[0].FirstLine != 2 || dups[0].LineNum != 10 {
+		t.Fatal("Duplicate section warning incorrect,", dups)
+	} else if dups[1].Key != "host" || dups[1].FirstLine != 3 || dups[1].LineNum != 11 {
+		t.Fatal("Duplicate key warning incorrect,", dups)
+	} else if d.Mysql.Host != "b.example" {
+		t.Fatal("Warn should keep the last value,", d.Mysql.Host)
+	}
+
+	dec = NewDecoder(bytes.NewReader(b))
+	dec.Duplicates(DuplicateFirst)
+	d = config{}
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	} else if d.Mysql.Host != "a.example" || len(dec.Unmatched()) != 0 {
+		t.Fatal("First should ignore the repeated section,", d.Mysql, dec.Unmatched())
+	}
+
+	dec = NewDecoder(bytes.NewReader(b))
+	dec.Duplicates(DuplicateError)
+	err := dec.Decode(&config{})
+	if err == nil || err.Error() != "Duplicate section [mysql] (first on line 2) on line 10: \"[MYSQL]\"" {
+		t.Fatal("Expected duplicate section error,", err)
+	}
+
+	err = Unmarshal([]byte("[MYSQL]\nUser=a\nUser=b"), &config{})
+	if err == nil || err.Error() != "Duplicate key user (first on line 2) on line 3: \"User=b\"" {
+		t.Fatal("Expected duplicate key error,", err)
+	}
+}
+
+func TestFixedArrays(t *testing.T) {
+	var d struct {
+		Gains   [3]float32
+		Origin  [2]int `ini:"Origin,split=,"`
+		Curves  [][]float64
+		Zones   [][2]int `ini:"Zone,split= "`
+		Weights [2][]int `ini:"Weight,split=;"`
+	}
+
+	b := []byte(`
+Gains=0.5
+Gains=1.5
+Origin=10, 20
+Curves=0, 0.5, 1
+Curves=0,0.25
+Zone=1 2
+Zone=3 4
+Weight=1;2;3
+Weight=4
+`)
+
+	err := Unmarshal(b, &d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Gains != [3]float32{0.5, 1.5, 0} {
+		t.Fatal("Gains incorrect,", d.Gains)
+	} else if d.Origin != [2]int{10, 20} {
+		t.Fatal("Origin incorrect,", d.Origin)
+	} else if len(d.Curves) != 2 || len(d.Curves[0]) != 3 || d.Curves[0][1] != 0.5 || d.Curves[1][1] != 0.25 {
+		t.Fatal("Curves incorrect,", d.Curves)
+	} else if len(d.Zones) != 2 || d.Zones[1] != [2]int{3, 4} {
+		t.Fatal("Zones incorrect,", d.Zones)
+	} else if len(d.Weights[0]) != 3 || d.Weights[1][0] != 4 {
+		t.Fatal("Weights incorrect,", d.Weights)
+	}
+
+	errs := map[string]string{
+		"Origin=1,2,3":                       `Too many values for origin, it holds 2 on line 1: "Origin=1,2,3"`,
+		"Gains=1\nGains=2\nGains=3\nGains=4": `Too many values for gains, it holds 3 on line 4: "Gains=4"`,
+		"Zone=1 2 3":                         `Expected 2 values for zone, got 3 on line 1: "Zone=1 2 3"`,
+		"Curves=1, x":                        `Invalid float on line 1: "Curves=1, x"`,
+	}
+	for s, msg := range errs {
+		err := Unmarshal([]byte(s), &d)
+		if err == nil || err.Error() != msg {
+			t.Fatalf("Expected error for %q, got %v", s, err)
+		}
+	}
+
+	var e struct {
+		Gains [3]float32 `ini:"Gains,exact"`
+		Zones [][2]int   `ini:"Zone,exact"`
+	}
+	err = Unmarshal([]byte("Gains=1\nGains=2\nZone=1,2"), &e)
+	if err == nil || err.Error() != `Expected 3 values for gains, got 2 on line 2: "Gains=2"` {
+		t.Fatal("Expected exact count error,", err)
+	}
+	err = Unmarshal([]byte("Zone=1"), &e)
+	if err == nil || err.Error() != `Expected 2 values for zone, got 1 on line 1: "Zone=1"` {
+		t.Fatal("Expected exact count error for inner array,", err)
+	}
+}
+
+type embeddedAuth struct {
+	User string
+	Pass string `ini:"password"`
+}
+
+type embeddedConn struct {
+	Host string
+	Port int
+}
+
+type embeddedTLS struct {
+	Cert string
+	Host string `ini:"Host"`
+}
+
+type EmbeddedLimits struct {
+	MaxConn int
+}
+
+func TestEmbeddedStructs(t *testing.T) {
+	var d struct {
+		embeddedAuth
+		*embeddedConn
+		*EmbeddedLimits
+		Conn2 struct {
+			embeddedConn
+			embeddedTLS
+		} `ini:"[CONN]"`
+		Name    string
+		secret  string
+		Skipped int `ini:"-"`
+		Port    int
+	}
+
+	b := []byte(`
+user=root
+password=hunter2
+host=a.example
+port=3306
+name=db
+maxconn=10
+secret=x
+skipped=1
+
+[CONN]
+host=b.example
+cert=b.pem
+port=99
+`)
+
+	err := Unmarshal(b, &d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.User != "root" || d.Pass != "hunter2" || d.Name != "db" {
+		t.Fatal("Promoted fields incorrect,", d.embeddedAuth, d.Name)
+	} else if d.embeddedConn != nil {
+		t.Fatal("Unexported embedded pointer should be left alone,", d.embeddedConn)
+	} else if d.EmbeddedLimits == nil || d.MaxConn != 10 {
+		t.Fatal("Embedded pointer should be allocated,", d.EmbeddedLimits)
+	} else if d.Port != 3306 || d.secret != "" || d.Skipped != 0 {
+		t.Fatal("Shallow, unexported or excluded fields incorrect,", d.Port, d.secret, d.Skipped)
+	} else if d.Conn2.embeddedTLS.Host != "b.example" || d.Conn2.embeddedConn.Host != "" {
+		t.Fatal("Tagged field should win a conflict,", d.Conn2)
+	} else if d.Conn2.Cert != "b.pem" || d.Conn2.embeddedConn.Port != 99 {
+		t.Fatal("Section promoted fields incorrect,", d.Conn2)
+	}
+
+	dec := NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	} else if u := dec.Unmatched(); len(u) != 3 || u[0].line != "host=a.example" || u[1].line != "secret=x" || u[2].line != "skipped=1" {
+		t.Fatal("Unmatched lines incorrect,", u)
+	}
+
+	// fields of the same name and depth without a tag cancel out
+	var c struct {
+		embeddedAuth
+		Other struct {
+			User string
+		} `ini:",inline"`
+	}
+	dec = NewDecoder(bytes.NewReader([]byte("User=root")))
+	if err := dec.Decode(&c); err != nil {
+		t.Fatal(err)
+	} else if c.User != "" || c.Other.User != "" || len(dec.Unmatched()) != 1 {
+		t.Fatal("Conflicting fields should be ignored,", c)
+	}
+
+	// "-" leaves out struct fields too; "inline" promotes them
+	var g struct {
+		Group struct {
+			Host string
+		} `ini:"-"`
+	}
+	dec = NewDecoder(bytes.NewReader([]byte("Host=a.example\n")))
+	if err := dec.Decode(&g); err != nil {
+		t.Fatal(err)
+	} else if g.Group.Host != "" || len(dec.Unmatched()) != 1 {
+		t.Fatal(`Struct tagged "-" should be left out,`, g)
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	b := []byte(`
+NAME=Tunes
+[DEFAULT]
+Rating=3
+[CREATE SONG]
+SongId=21348
+Title=Long Way to Go
+[CREATE SONG]
+SongId=9855
+Rating=4.5
+[CREATE PLAYLIST]
+Song=21348
+Song=482
+Tag[]=jazz
+Limits[plays]=10
+Live=yes
+`)
+
+	var v interface{}
+	dl := DefaultDialect
+	dl.DefaultSection = "DEFAULT"
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Dialect(dl)
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	root, ok := v.(map[string]interface{})
+	if !ok || root["name"] != "Tunes" {
+		t.Fatal("Top level keys incorrect,", v)
+	}
+
+	songs, ok := root["create song"].([]interface{})
+	if !ok || len(songs) != 2 {
+		t.Fatal("Repeated sections incorrect,", root["create song"])
+	}
+	song0, song1 := songs[0].(map[string]interface{}), songs[1].(map[string]interface{})
+	if song0["songid"] != "21348" || song0["title"] != "Long Way to Go" || song0["rating"] != "3" {
+		t.Fatal("Songs[0] incorrect,", song0)
+	} else if song1["rating"] != "4.5" {
+		t.Fatal("Songs[1] should override the default,", song1)
+	}
+
+	playlist := root["create playlist"].(map[string]interface{})
+	if ids, ok := playlist["song"].([]interface{}); !ok || len(ids) != 2 || ids[1] != "482" {
+		t.Fatal("Repeated keys incorrect,", playlist["song"])
+	} else if tags, ok := playlist["tag"].([]interface{}); !ok || len(tags) != 1 {
+		t.Fatal("Array key incorrect,", playlist["tag"])
+	} else if limits, ok := playlist["limits"].(map[string]interface{}); !ok || limits["plays"] != "10" {
+		t.Fatal("Map key incorrect,", playlist["limits"])
+	}
+
+	m := map[string]interface{}{"keep": true}
+	dec = NewDecoder(bytes.NewReader(b))
+	dec.InferTypes()
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	} else if m["keep"] != true || m["name"] != "Tunes" {
+		t.Fatal("Decoding into a map incorrect,", m)
+	}
+
+	songs = m["create song"].([]interface{})
+	playlist = m["create playlist"].(map[string]interface{})
+	if songs[0].(map[string]interface{})["songid"] != int64(21348) || songs[1].(map[string]interface{})["rating"] != 4.5 {
+		t.Fatal("Inferred numbers incorrect,", songs)
+	} else if playlist["live"] != true {
+		t.Fatal("Inferred bool incorrect,", playlist["live"])
+	}
+}
+
+// A section created by a dotted path, like server by [server.tls], is
+// not repeated by its own first header.
+func TestUnmarshalInterfaceDotted(t *testing.T) {
+	var m map[string]interface{}
+	b := []byte("[server.tls]\ncert=x\n[server]\nhost=h\n[server.tls]\ncert=y\n[server]\nhost=h2\n[server.tls.ca]\nfile=z")
+	if err := UnmarshalWith(b, &m, WithDottedSections()); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, ok := m["server"].([]interface{})
+	if !ok || len(servers) != 2 {
+		t.Fatal("Server should be repeated once,", m["server"])
+	}
+
+	first := servers[0].(map[string]interface{})
+	if tls, ok := first["tls"].([]interface{}); !ok || len(tls) != 2 || first["host"] != "h" {
+		t.Fatal("First server incorrect,", first)
+	}
+
+	second := servers[1].(map[string]interface{})
+	tls, ok := second["tls"].(map[string]interface{})
+	if !ok || second["host"] != "h2" || tls["ca"].(map[string]interface{})["file"] != "z" {
+		t.Fatal("Second server incorrect,", second)
+	}
+}
+
+func TestUnmarshalInterfaceConflicts(t *testing.T) {
+	errs := map[string]string{
+		"server=x\n[server]\nhost=h":            `Section [server] conflicts with key server on line 2: "[server]"`,
+		"[server]\nhost=h\n[@prod]\nserver=x":   `Conflicting values of server on line 3: "[@prod]"`,
+		"limits=5\nlimits[cpu]=2":               `Key limits[cpu] conflicts with key limits on line 2: "limits[cpu]=2"`,
+		"limits[cpu]=2\nlimits=5":               `Key limits conflicts with map limits on line 2: "limits=5"`,
+		"[server.tls]\ncert=c\n[server]\ntls=x": `Key tls conflicts with section [tls] on line 4: "tls=x"`,
+		"[server]\ntls=x\n[server.tls]\ncert=c": `Section [server.tls] conflicts with key tls on line 3: "[server.tls]"`,
+	}
+	for b, want := range errs {
+		var v interface{}
+		err := UnmarshalWith([]byte(b), &v, WithDottedSections(), WithProfile("prod"))
+		if err == nil || err.Error() != want {
+			t.Fatalf("Expected %q for %q, got %v", want, b, err)
+		}
+	}
+
+	var n int
+	if err := Unmarshal([]byte("n=1"), &n); err == nil || err.Error() != "Can't decode into value of type int" {
+		t.Fatal("Expected error decoding into int,", err)
+	}
+	var p *struct{ N int }
+	if err := Unmarshal([]byte("n=1"), &p); err != nil || p == nil || p.N != 1 {
+		t.Fatal("Expected nil pointer to be allocated,", p, err)
+	}
+	if err := Unmarshal([]byte("n=1"), struct{}{}); err == nil {
+		t.Fatal("Expected error decoding into a non-pointer")
+	}
+}
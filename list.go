@@ -0,0 +1,176 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// An arrayCount is the number of values set in a fixed size array, and
+// where the last one was set.
+type arrayCount struct {
+	name    string
+	n, size int
+	exact   bool
+	lineNum int
+	line    string
+}
+
+// Reports whether a type is filled from a list of values: a slice other
+// than RawValue, or a fixed size array.
+func isList(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice && t != rawValueType) || t.Kind() == reflect.Array
+}
+
+// Empties a list or map, so the next value starts it over.
+func (d *decodeState) resetList(prop property) {
+	prop.value.Set(reflect.Zero(prop.value.Type()))
+	if k, ok := keyOf(prop.value); ok {
+		delete(d.counts, k)
+	}
+}
+
+/*
+ * Adds a value to the list of a property. A slice grows, a fixed size
+ * array is filled by position and it is an error to give it more values
+ * than it holds.
+ */
+func (d *decodeState) appendElement(prop property, value reflect.Value) {
+	if prop.value.Kind() == reflect.Slice {
+		prop.value.Set(reflect.Append(prop.value, value))
+		return
+	}
+
+	k, _ := keyOf(prop.value)
+	c := d.counts[k]
+	if c.n >= prop.value.Len() {
+		d.saveError(&IniError{d.lineNum, d.line, fmt.Sprintf("Too many values for %s, it holds %d", prop.tag, prop.value.Len())})
+		return
+	}
+
+	prop.value.Index(c.n).Set(value)
+	if d.counts == nil {
+		d.counts = make(map[fieldKey]arrayCount)
+	}
+	d.counts[k] = arrayCount{prop.tag, c.n + 1, prop.value.Len(), prop.exact, d.lineNum, d.line}
+}
+
+// Fills the inner list of a [][]T or [N][]T from one line, split at the
+// separator of the property, a comma unless set.
+func (d *decodeState) setList(prop property, v reflect.Value, s string) {
+	sep := prop.split
+	if len(sep) == 0 {
+		sep = ","
+	}
+
+	elems, err := splitList(s, sep, prop.quoted)
+	if err != nil {
+		d.saveError(&IniError{d.lineNum, d.line, err.Error()})
+		return
+	}
+
+	if v.Kind() == reflect.Array {
+		if len(elems) > v.Len() || (prop.exact && len(elems) != v.Len()) {
+			d.saveError(&IniError{d.lineNum, d.line, fmt.Sprintf("Expected %d values for %s, got %d", v.Len(), prop.tag, len(elems))})
+			return
+		}
+	} else {
+		v.Set(reflect.MakeSlice(v.Type(), len(elems), len(elems)))
+	}
+
+	for i, elem := range elems {
+		d.setValue(v.Index(i), elem)
+	}
+}
+
+// Reports the first array with the "exact" option that did not get as
+// many values as it holds, on the line of its last value.
+func (d *decodeState) checkCounts() {
+	var short []arrayCount
+	for _, c := range d.counts {
+		if c.exact && c.n < c.size {
+			short = append(short, c)
+		}
+	}
+	if len(short) == 0 {
+		return
+	}
+
+	sort.Slice(short, func(i, j int) bool { return short[i].lineNum < short[j].lineNum })
+	c := short[0]
+	d.saveError(&IniError{c.lineNum, c.line, fmt.Sprintf("Expected %d values for %s, got %d", c.size, c.name, c.n)})
+}
+
+/*
+ * Splits a list value like "a.example, b.example" at sep, trimming the
+ * elements. A separator of whitespace splits at any run of whitespace.
+ * With quoted, an element may be a quoted string holding separators,
+ * read like a quoted value. An empty value is an empty list.
+ */
+func splitList(s, sep string, quoted bool) ([]string, error) {
+	space := len(strings.TrimSpace(sep)) == 0
+
+	var elems []string
+	for s = strings.TrimSpace(s); len(s) > 0; s = strings.TrimLeftFunc(s, unicode.IsSpace) {
+		var elem string
+		switch {
+		case quoted && (s[0] == '"' || s[0] == '\''):
+			value, rest, err := unquotePrefix(s)
+			if err != nil {
+				return nil, err
+			}
+			elem, s = value, strings.TrimLeftFunc(rest, unicode.IsSpace)
+			if space && len(s) > 0 && len(s) == len(rest) {
+				return nil, errQuote
+			} else if !space && len(s) > 0 {
+				if !strings.HasPrefix(s, sep) {
+					return nil, errQuote
+				}
+				s = s[len(sep):]
+			}
+
+		case space:
+			i := strings.IndexFunc(s, unicode.IsSpace)
+			if i < 0 {
+				i = len(s)
+			}
+			elem, s = s[:i], s[i:]
+
+		default:
+			i := strings.Index(s, sep)
+			if i < 0 {
+				elem, s = strings.TrimSpace(s), ""
+			} else {
+				elem, s = strings.TrimSpace(s[:i]), s[i+len(sep):]
+			}
+		}
+		elems = append(elems, elem)
+	}
+
+	return elems, nil
+}
+
+// Joins the elements of a list with sep, the reverse of splitList.
+// Reports false if an element can't be read back unchanged.
+func joinList(elems []string, sep string, quoted bool) (string, bool) {
+	space := len(strings.TrimSpace(sep)) == 0
+
+	for i, elem := range elems {
+		mustQuote := len(elem) == 0 || elem != strings.TrimSpace(elem) ||
+			(space && strings.IndexFunc(elem, unicode.IsSpace) >= 0) ||
+			(!space && strings.Contains(elem, sep))
+
+		if quoted && (mustQuote || elem[0] == '"' || elem[0] == '\'' || strings.ContainsAny(elem, "\\\n")) {
+			elems[i] = quote(elem)
+		} else if mustQuote && !(len(elem) == 0 && !space && i < len(elems)-1) {
+			return "", false
+		}
+	}
+
+	if !space {
+		sep += " "
+	}
+	return strings.Join(elems, sep), true
+}
@@ -0,0 +1,105 @@
+package ini
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+)
+
+// An Option configures a Decoder. Options are applied in order.
+type Option func(*Decoder)
+
+// A ValueHook can set a value itself, for types the Decoder doesn't
+// know, like time.Duration, or to change how a value is read. It
+// returns true when it set v, and false to let the Decoder set it. An
+// error stops decoding, reported on the line of the value.
+type ValueHook func(v reflect.Value, s string) (bool, error)
+
+// UnmarshalWith is like Unmarshal, with a Decoder configured by opts.
+func UnmarshalWith(data []byte, v interface{}, opts ...Option) error {
+	return NewDecoder(bytes.NewReader(data), opts...).Decode(v)
+}
+
+// WithDialect sets the syntax of the file, see Decoder.Dialect.
+func WithDialect(dl Dialect) Option {
+	return func(dec *Decoder) { dec.Dialect(dl) }
+}
+
+// WithStrict makes a line that matches no field an error, instead of
+// an entry of Decoder.Unmatched.
+func WithStrict() Option {
+	return func(dec *Decoder) { dec.d.strict = true }
+}
+
+// WithCaseSensitiveKeys matches keys and section names against tags
+// exactly, whatever the dialect, see Dialect.CaseSensitive.
+func WithCaseSensitiveKeys() Option {
+	return func(dec *Decoder) { dec.d.exactKeys = true }
+}
+
+// WithMaxLineLength sets the longest line, in bytes, the Decoder
+// reads. A longer line is an error. The default is 64KB.
+func WithMaxLineLength(n int) Option {
+	return func(dec *Decoder) { dec.d.maxLine = n }
+}
+
+// WithLogger logs unmatched lines at debug level, and duplicates with
+// the DuplicateWarn policy at warning level when there is no
+// OnDuplicate function.
+func WithLogger(logger *slog.Logger) Option {
+	return func(dec *Decoder) { dec.d.logger = logger }
+}
+
+// WithTagName names fields by another struct tag key than "ini", as in
+// `cfg:"Port"`.
+func WithTagName(name string) Option {
+	return func(dec *Decoder) { dec.d.tagName = name }
+}
+
+// WithValueHook sets a function that is given every value before the
+// Decoder sets it.
+func WithValueHook(fn ValueHook) Option {
+	return func(dec *Decoder) { dec.d.valueHook = fn }
+}
+
+// WithDottedSections reads dotted headers as paths of sections, see
+// Decoder.DottedSections.
+func WithDottedSections() Option {
+	return func(dec *Decoder) { dec.DottedSections() }
+}
+
+// WithScoping changes how headers and keys are matched to sections,
+// see Scoping.
+func WithScoping(s Scoping) Option {
+	return func(dec *Decoder) { dec.Scoping(s) }
+}
+
+// WithProfile selects the profile whose sections are decoded, see
+// Decoder.Profile.
+func WithProfile(name string) Option {
+	return func(dec *Decoder) { dec.Profile(name) }
+}
+
+// WithDuplicates sets the policy for duplicate keys and sections, see
+// Decoder.Duplicates.
+func WithDuplicates(p DuplicatePolicy) Option {
+	return func(dec *Decoder) { dec.Duplicates(p) }
+}
+
+// WithOnDuplicate sets the function called for every duplicate with the
+// DuplicateWarn policy, see Decoder.OnDuplicate.
+func WithOnDuplicate(fn func(Duplicate)) Option {
+	return func(dec *Decoder) { dec.OnDuplicate(fn) }
+}
+
+// WithInferTypes stores values read without a schema as numbers and
+// booleans, see Decoder.InferTypes.
+func WithInferTypes() Option {
+	return func(dec *Decoder) { dec.InferTypes() }
+}
+
+// WithTracer sets a Tracer that receives every step of decoding, see
+// Decoder.Trace and SlogTracer.
+func WithTracer(t Tracer) Option {
+	return func(dec *Decoder) { dec.Trace(t) }
+}
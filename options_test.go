@@ -0,0 +1,103 @@
+package ini
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalWith(t *testing.T) {
+	var d struct {
+		Host    string        `cfg:"host"`
+		Timeout time.Duration `cfg:"timeout"`
+		Port    int           `ini:"port" cfg:"-"`
+	}
+
+	b := []byte(`
+host=localhost
+timeout=1m30s
+Port=3306
+`)
+
+	hook := func(v reflect.Value, s string) (bool, error) {
+		if v.Type() != reflect.TypeOf(time.Duration(0)) {
+			return false, nil
+		}
+		n, err := time.ParseDuration(s)
+		v.SetInt(int64(n))
+		return true, err
+	}
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	err := UnmarshalWith(b, &d, WithTagName("cfg"), WithValueHook(hook), WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Host != "localhost" || d.Timeout != 90*time.Second || d.Port != 0 {
+		t.Fatal("Tag name or value hook incorrect,", d)
+	} else if !strings.Contains(log.String(), "unmatched line") || !strings.Contains(log.String(), "Port=3306") {
+		t.Fatal("Unmatched line not logged,", log.String())
+	}
+
+	err = UnmarshalWith([]byte("timeout=soon"), &d, WithTagName("cfg"), WithValueHook(hook))
+	if err == nil || !strings.HasPrefix(err.Error(), "time: invalid duration") {
+		t.Fatal("Expected value hook error,", err)
+	}
+
+	err = UnmarshalWith(b, &d, WithTagName("cfg"), WithValueHook(hook), WithStrict())
+	if err == nil || err.Error() != `Unmatched line on line 4: "Port=3306"` {
+		t.Fatal("Expected strict error,", err)
+	}
+
+	err = UnmarshalWith(b, &d, WithDialect(DefaultDialect), WithCaseSensitiveKeys(), WithStrict())
+	if err == nil || err.Error() != `Unmatched line on line 2: "host=localhost"` {
+		t.Fatal("Expected case sensitive keys,", err)
+	}
+
+	err = UnmarshalWith(b, &d, WithCaseSensitiveKeys(), WithDialect(PythonDialect), WithStrict())
+	if err == nil || err.Error() != `Unmatched line on line 2: "host=localhost"` {
+		t.Fatal("Expected case sensitive keys after a later dialect,", err)
+	}
+
+	long := []byte("port=1\nhost=" + strings.Repeat("x", 100) + "\n")
+	err = UnmarshalWith(long, &d, WithMaxLineLength(50))
+	if err == nil || err.Error() != `Line longer than 50 bytes on line 2: ""` {
+		t.Fatal("Expected line length error,", err)
+	} else if err := UnmarshalWith(long, &d, WithMaxLineLength(105)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOptionsDecoder(t *testing.T) {
+	var d profileConfig
+
+	b := []byte(`
+[MYSQL]
+Host=localhost
+[MYSQL@prod]
+Host=db.example.com
+Host=db2.example.com
+`)
+
+	var dups []Duplicate
+	dec := NewDecoder(bytes.NewReader(b), WithProfile("prod"),
+		WithDuplicates(DuplicateWarn), WithOnDuplicate(func(dup Duplicate) { dups = append(dups, dup) }))
+	if err := dec.Decode(&d); err != nil {
+		t.Fatal(err)
+	} else if d.MySQL.Host != "db2.example.com" {
+		t.Fatal("Profile option incorrect,", d.MySQL.Host)
+	}
+
+	var m map[string]interface{}
+	if err := UnmarshalWith([]byte("[a.b]\nn=1"), &m, WithDottedSections(), WithInferTypes()); err != nil {
+		t.Fatal(err)
+	} else if a, ok := m["a"].(map[string]interface{}); !ok || a["b"].(map[string]interface{})["n"] != int64(1) {
+		t.Fatal("Dotted sections and inferred types incorrect,", m)
+	}
+}
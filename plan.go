@@ -0,0 +1,90 @@
+package ini
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// A fieldPlan is how a field of a struct is decoded, worked out from
+// its type and tag once and shared by every Decoder, like encoding/json
+// caches its fields.
+type fieldPlan struct {
+	tag        string // name matched in the file, folded
+	index      []int  // see fieldByIndex
+	isStruct   bool   // section with children of its own
+	isSection  bool
+	isArray    bool
+	isMap      bool
+	dotted     bool
+	split      string
+	quoted     bool
+	duplicates DuplicatePolicy
+	exact      bool
+	err        error // invalid tag option, reported when decoding
+}
+
+// A planKey identifies the plans of a type, which depend on the tag
+// key and on whether names are folded.
+type planKey struct {
+	typ           reflect.Type
+	tagName       string
+	caseSensitive bool
+}
+
+var (
+	planCache  sync.Map // map[planKey][]fieldPlan
+	fieldCache sync.Map // map[fieldCacheKey][]field
+)
+
+type fieldCacheKey struct {
+	typ     reflect.Type
+	tagName string
+}
+
+// Returns the plans of the fields of a struct type, from the cache.
+func (d *decodeState) plan(t reflect.Type) []fieldPlan {
+	key := planKey{t, d.tag(), d.dialect.CaseSensitive}
+	if plans, ok := planCache.Load(key); ok {
+		return plans.([]fieldPlan)
+	}
+
+	var plans []fieldPlan
+	for _, tf := range cachedTypeFields(t, key.tagName) {
+		ft := t.FieldByIndex(tf.index).Type
+		kind := ft.Kind()
+		opts := tf.opts
+
+		p := fieldPlan{
+			tag:       strings.TrimSpace(d.dialect.fold(tf.name)),
+			index:     tf.index,
+			isStruct:  kind == reflect.Struct,
+			isSection: kind == reflect.Struct || (kind == reflect.Slice && ft.Elem().Kind() == reflect.Struct),
+			isArray:   isList(ft),
+			isMap:     kind == reflect.Map,
+			dotted:    opts.Contains("dotted"),
+			quoted:    opts.Contains("quoted"),
+			exact:     opts.Contains("exact"),
+		}
+		p.split, _ = opts.Get("split")
+
+		var check decodeState
+		p.duplicates = check.parseDuplicates(opts)
+		p.err = check.savedError
+
+		plans = append(plans, p)
+	}
+
+	actual, _ := planCache.LoadOrStore(key, plans)
+	return actual.([]fieldPlan)
+}
+
+// Returns the fields of a struct type, from the cache.
+func cachedTypeFields(t reflect.Type, tagName string) []field {
+	key := fieldCacheKey{t, tagName}
+	if fields, ok := fieldCache.Load(key); ok {
+		return fields.([]field)
+	}
+	actual, _ := fieldCache.LoadOrStore(key, typeFields(t, tagName))
+	return actual.([]field)
+}
@@ -0,0 +1,129 @@
+package ini
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type planDevice struct {
+	Name     string
+	Firmware string `ini:"firmware"`
+	Network  struct {
+		Address string
+		Mask    string
+		DNS     []string `ini:"dns"`
+	} `ini:"[network]"`
+	Sensors []struct {
+		Id     int
+		Kind   string
+		Limits map[string]float64 `ini:"limits"`
+	} `ini:"[sensor]"`
+}
+
+var planData = []byte(`
+Name=thermostat-12
+firmware=2.4.1
+
+[network]
+Address=10.0.0.12
+Mask=255.255.255.0
+dns=10.0.0.1
+dns=10.0.0.2
+
+[sensor]
+Id=1
+Kind=temperature
+limits[min]=-20
+limits[max]=60
+
+[sensor]
+Id=2
+Kind=humidity
+`)
+
+func TestPlanCache(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	devices := make([]planDevice, 8)
+	for i := range devices {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Unmarshal(planData, &devices[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, d := range devices {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		} else if d.Name != "thermostat-12" || len(d.Network.DNS) != 2 || len(d.Sensors) != 2 || d.Sensors[0].Limits["min"] != -20 {
+			t.Fatal("Device decoded incorrectly,", d)
+		}
+	}
+
+	var d decodeState
+	d.dialect = DefaultDialect
+	typ := reflect.TypeOf(planDevice{})
+	plans := d.plan(typ)
+	if len(plans) != 4 || plans[1].tag != "firmware" || !plans[2].isStruct || !plans[3].isSection || !plans[3].isArray {
+		t.Fatal("Incorrect plan,", plans)
+	} else if &d.plan(typ)[0] != &plans[0] {
+		t.Fatal("Plan not cached")
+	}
+
+	d.dialect.CaseSensitive = true
+	if d.plan(typ)[0].tag != "Name" {
+		t.Fatal("Plan should depend on case sensitivity,", d.plan(typ)[0])
+	}
+
+	var bad struct {
+		Port int `ini:"Port,dup=never"`
+	}
+	for i := 0; i < 2; i++ {
+		err := Unmarshal([]byte("Port=1"), &bad)
+		if err == nil || err.Error() != `Invalid dup option "never"` {
+			t.Fatal("Expected tag error from the cached plan,", err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var d planDevice
+		if err := Unmarshal(planData, &d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Decodes without the cache, clearing it every time, to compare with
+// BenchmarkUnmarshal.
+func BenchmarkUnmarshalUncached(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		planCache.Clear()
+		fieldCache.Clear()
+
+		var d planDevice
+		if err := Unmarshal(planData, &d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	var d planDevice
+	if err := Unmarshal(planData, &d); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(&d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
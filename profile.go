@@ -0,0 +1,57 @@
+package ini
+
+import (
+	"strings"
+)
+
+// Returns the separator between a section name and its profile.
+func (dl *Dialect) profileSeparator() string {
+	if len(dl.ProfileSeparator) == 0 {
+		return "@"
+	}
+	return dl.ProfileSeparator
+}
+
+// Splits a header [name@profile] into [name] and profile.
+func (d *decodeState) splitProfile(header string) (string, string) {
+	name := header
+	if isBracketed(name) {
+		name = name[1 : len(name)-1]
+	}
+
+	i := strings.LastIndex(name, d.dialect.profileSeparator())
+	if i < 0 {
+		return header, ""
+	}
+
+	profile := strings.TrimSpace(name[i+len(d.dialect.profileSeparator()):])
+	return "[" + strings.TrimSpace(name[:i]) + "]", profile
+}
+
+/*
+ * Reports whether a property may be set. A field set by the active
+ * profile keeps its value when plain sections set it again, and the
+ * first key of a profile section replaces a list or map instead of
+ * adding to it.
+ */
+func (d *decodeState) override(prop property, ev event) bool {
+	k, ok := keyOf(prop.value)
+	if !ok || len(d.profile) == 0 {
+		return true
+	}
+
+	if !ev.section.active {
+		return !d.overridden[k]
+	}
+
+	if !d.overridden[k] {
+		if d.overridden == nil {
+			d.overridden = make(map[fieldKey]bool)
+		}
+		d.overridden[k] = true
+		if prop.isArray || prop.isMap {
+			d.resetList(prop)
+		}
+	}
+	return true
+}
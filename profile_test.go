@@ -0,0 +1,185 @@
+package ini
+
+import (
+	"bytes"
+	"testing"
+)
+
+type profileConfig struct {
+	Name  string `ini:"NAME"`
+	Debug bool
+	MySQL struct {
+		Host  string
+		Port  int
+		Hosts []string `ini:"Replica"`
+		TLS   struct {
+			Cert string
+		} `ini:"[TLS]"`
+	} `ini:"[MYSQL]"`
+	Songs []struct {
+		Title string
+	} `ini:"[SONG]"`
+}
+
+func TestProfile(t *testing.T) {
+	var d profileConfig
+
+	b := []byte(`
+NAME=tunes
+
+[MYSQL@prod]
+Host=db.example.com
+Replica=r1.example.com
+
+[@prod]
+Debug=false
+
+[MYSQL]
+Host=localhost
+Port=3306
+Replica=localhost
+
+[MYSQL@test]
+Host=test.example.com
+Unknown=1
+
+[SONG]
+Title=Long Way to Go
+
+[SONG@prod]
+Title=The Falcon Lead
+`)
+
+	dec := NewDecoder(bytes.NewReader(b))
+	dec.Profile("prod")
+	d.Debug = true
+	err := dec.Decode(&d)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Name != "tunes" || d.Debug {
+		t.Fatal("Top level keys incorrect,", d.Name, d.Debug)
+	} else if d.MySQL.Host != "db.example.com" {
+		t.Fatal("Profile should override wherever it appears,", d.MySQL.Host)
+	} else if d.MySQL.Port != 3306 {
+		t.Fatal("Keys without override incorrect,", d.MySQL.Port)
+	} else if len(d.MySQL.Hosts) != 1 || d.MySQL.Hosts[0] != "r1.example.com" {
+		t.Fatal("Profile list should replace the list,", d.MySQL.Hosts)
+	} else if len(d.Songs) != 1 || d.Songs[0].Title != "The Falcon Lead" {
+		t.Fatal("Profile should continue the last repeated section,", d.Songs)
+	} else if len(dec.Unmatched()) != 0 {
+		t.Fatal("Other profiles should be skipped,", dec.Unmatched())
+	}
+
+	// without a profile, profile sections are not sections
+	var plain profileConfig
+	dec = NewDecoder(bytes.NewReader(b))
+	if err := dec.Decode(&plain); err != nil {
+		t.Fatal(err)
+	} else if plain.MySQL.Host != "localhost" {
+		t.Fatal("Host without profile incorrect,", plain.MySQL.Host)
+	}
+
+	var m map[string]interface{}
+	dec = NewDecoder(bytes.NewReader(b))
+	dec.Profile("prod")
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	} else if mysql := m["mysql"].(map[string]interface{}); mysql["host"] != "db.example.com" || mysql["port"] != "3306" {
+		t.Fatal("Profile without a schema incorrect,", mysql)
+	} else if m["debug"] != "false" {
+		t.Fatal("Top level profile keys without a schema incorrect,", m)
+	}
+}
+
+func TestMarshalProfile(t *testing.T) {
+	var base, prod profileConfig
+	base.Name = "tunes"
+	base.MySQL.Host = "localhost"
+	base.MySQL.Port = 3306
+	base.MySQL.Hosts = []string{"localhost"}
+
+	prod = base
+	prod.MySQL.Hosts = []string{"r1", "r2"}
+	prod.MySQL.TLS.Cert = "prod.pem"
+	prod.Debug = true
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Profile("prod", &base)
+	if err := enc.Encode(&prod); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[@prod]
+Debug=true
+
+[MYSQL@prod]
+Replica=r1
+Replica=r2
+
+[TLS@prod]
+Cert=prod.pem
+`
+	if buf.String() != expected {
+		t.Fatalf("Profile output incorrect:\n%s", buf.String())
+	}
+
+	b, err := Marshal(&base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var r profileConfig
+	dec := NewDecoder(bytes.NewReader(append(b, buf.Bytes()...)))
+	dec.Profile("prod")
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+
+	if !r.Debug || r.MySQL.Host != "localhost" || r.MySQL.TLS.Cert != "prod.pem" {
+		t.Fatal("Round trip lost values:", r)
+	} else if len(r.MySQL.Hosts) != 2 || r.MySQL.Hosts[1] != "r2" {
+		t.Fatal("Round trip Hosts incorrect,", r.MySQL.Hosts)
+	}
+
+	// a nested override writes the header of its parent
+	prod = base
+	prod.MySQL.TLS.Cert = "prod.pem"
+	buf.Reset()
+	if err := enc.Encode(&prod); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "[MYSQL@prod]\n\n[TLS@prod]\nCert=prod.pem\n" {
+		t.Fatalf("Nested profile output incorrect:\n%s", buf.String())
+	}
+
+	prod.Songs = make([]struct{ Title string }, 1)
+	if err := enc.Encode(&prod); err == nil {
+		t.Fatal("Expected error for repeated section in profile")
+	}
+
+	// emptying a list needs a key that starts it over
+	prod = base
+	prod.MySQL.Hosts = nil
+	if err := enc.Encode(&prod); err == nil || err.Error() != "Can't encode profile emptying list Replica in this dialect" {
+		t.Fatal("Expected error for emptied list in profile,", err)
+	}
+
+	buf.Reset()
+	enc.Dialect(SystemdDialect)
+	if err := enc.Encode(&prod); err != nil {
+		t.Fatal(err)
+	} else if buf.String() != "[MYSQL@prod]\nReplica=\n" {
+		t.Fatalf("Emptied list output incorrect:\n%s", buf.String())
+	}
+
+	r = profileConfig{}
+	dec = NewDecoder(bytes.NewReader(append(b, buf.Bytes()...)), WithDialect(SystemdDialect), WithProfile("prod"))
+	if err := dec.Decode(&r); err != nil {
+		t.Fatal(err)
+	} else if len(r.MySQL.Hosts) != 0 || r.MySQL.Host != "localhost" {
+		t.Fatal("Emptied list should read back empty,", r.MySQL)
+	}
+}
@@ -0,0 +1,182 @@
+package ini
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Quoting selects how quoted values are read and written.
+type Quoting int
+
+const (
+	// QuoteNone takes every value literally, quotes included.
+	QuoteNone Quoting = iota
+
+	// QuoteEscape reads "..." values with the escapes \n, \t, \r, \",
+	// \', \\ and \uXXXX, and '...' values literally. Whitespace inside
+	// the quotes is kept.
+	QuoteEscape
+
+	// QuoteStrip removes a single pair of matching quotes around a
+	// value, without escapes.
+	QuoteStrip
+)
+
+// RawValue is a value exactly as it appears in the INI file, after
+// the delimiter and without surrounding whitespace, before quotes,
+// escapes and inline comments are processed. It can be used as a field
+// type to get at the unprocessed text.
+type RawValue []byte
+
+var rawValueType = reflect.TypeOf(RawValue(nil))
+
+var (
+	errQuote  = errors.New("Invalid quoted value")
+	errEscape = errors.New("Invalid escape sequence")
+)
+
+/*
+ * Processes quotes and inline comments in a value, as set by the
+ * dialect. Inline comments start at the start of the value or after
+ * whitespace, outside of quotes.
+ */
+func unquote(s string, dl *Dialect) (string, error) {
+	s = strings.TrimSpace(s)
+
+	if dl.Quoting == QuoteStrip {
+		if len(s) > 1 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+			return s[1 : len(s)-1], nil
+		}
+	} else if dl.Quoting != QuoteNone && len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		value, rest, err := unquotePrefix(s)
+		if err != nil {
+			return "", err
+		}
+
+		rest = strings.TrimSpace(rest)
+		if len(rest) > 0 && !(dl.InlineComments && dl.isComment(rest)) {
+			return "", errQuote
+		}
+		return value, nil
+	}
+
+	if dl.InlineComments {
+		s = dl.stripComment(s)
+	}
+
+	return s, nil
+}
+
+// Reads the quoted string at the start of s, returning its value and
+// whatever follows the closing quote.
+func unquotePrefix(s string) (value, rest string, err error) {
+	q := s[0]
+	if q == '\'' {
+		end := strings.IndexByte(s[1:], q)
+		if end < 0 {
+			return "", "", errQuote
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == q:
+			return b.String(), s[i+1:], nil
+
+		case c != '\\':
+			b.WriteByte(c)
+
+		case i+1 >= len(s):
+			return "", "", errEscape
+
+		default:
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\'', '\\':
+				b.WriteByte(s[i])
+			case 'u':
+				r, n := unquoteRune(s[i+1:])
+				if n == 0 {
+					return "", "", errEscape
+				}
+				b.WriteRune(r)
+				i += n
+			default:
+				return "", "", errEscape
+			}
+		}
+	}
+
+	return "", "", errQuote
+}
+
+// Reads the XXXX of a \uXXXX escape, and a second \uXXXX escape when
+// the first is a UTF-16 surrogate. Returns the number of bytes used.
+func unquoteRune(s string) (rune, int) {
+	if len(s) < 4 {
+		return 0, 0
+	}
+
+	n, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0
+	}
+
+	r := rune(n)
+	if utf16.IsSurrogate(r) && len(s) >= 10 && s[4:6] == "\\u" {
+		if n2, err := strconv.ParseUint(s[6:10], 16, 16); err == nil {
+			if dec := utf16.DecodeRune(r, rune(n2)); dec != utf8.RuneError {
+				return dec, 10
+			}
+		}
+	}
+
+	return r, 4
+}
+
+// Reports whether s must be quoted to read back unchanged.
+func needsQuotes(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	if s != strings.TrimSpace(s) || s[0] == '"' || s[0] == '\'' {
+		return true
+	}
+	return strings.ContainsAny(s, ";#\n\r\t\\")
+}
+
+// Returns s as a double quoted value with escapes.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package ini
+
+import (
+	"io"
+	"strings"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+// Kinds of tokens, in the order of lineKind.
+const (
+	// TokenBlank is an empty line, or one holding only whitespace.
+	TokenBlank TokenKind = iota
+
+	// TokenComment is a line starting with a comment prefix.
+	TokenComment
+
+	// TokenSectionHeader is a section header like [NAME], or a line
+	// without a delimiter when the dialect doesn't require brackets.
+	TokenSectionHeader
+
+	// TokenKeyValue is a NAME=VALUE line, a heredoc or continued value,
+	// or a bare key when the dialect has BareKeys.
+	TokenKeyValue
+
+	// TokenBareLine is a line without a delimiter that is neither a
+	// header nor a key, when the dialect requires brackets.
+	TokenBareLine
+)
+
+var tokenKindNames = []string{"Blank", "Comment", "SectionHeader", "KeyValue", "BareLine"}
+
+func (k TokenKind) String() string {
+	if k >= 0 && int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return "Unknown"
+}
+
+// A Token is a logical line of an INI file: a single line, or the
+// lines of a value continued over several.
+type Token struct {
+	Kind TokenKind
+
+	// Line is the line the token starts on, counted from 1.
+	Line int
+
+	// Offset and End are the byte offsets of the start of the token
+	// and just past its last character, before the line break.
+	Offset int64
+	End    int64
+
+	// Raw holds the lines of the token as read, joined by newlines.
+	Raw string
+
+	// Name is the trimmed section name without brackets, or the key as
+	// written, like extension[].
+	Name string
+
+	// Value is the value of a key, trimmed and unquoted the way the
+	// dialect reads it.
+	Value string
+}
+
+/*
+ * A Scanner reads the tokens of an INI file, the way Unmarshal does
+ * before matching them to fields. It can be used by tools that read the
+ * syntax of a file without decoding it.
+ */
+type Scanner struct {
+	d decodeState
+}
+
+// NewScanner returns a Scanner reading from r in DefaultDialect. Of
+// the options, only WithDialect, WithCaseSensitiveKeys and
+// WithMaxLineLength apply.
+func NewScanner(r io.Reader, opts ...Option) *Scanner {
+	dec := NewDecoder(r, opts...)
+	s := &Scanner{}
+	s.d.dialect, s.d.maxLine = dec.d.dialect, dec.d.maxLine
+	if dec.d.exactKeys {
+		s.d.dialect.CaseSensitive = true
+	}
+	s.d.reset()
+	s.d.scanner = s.d.newScanner(r)
+	return s
+}
+
+/*
+ * Next returns the next token. At the end of the input it returns
+ * io.EOF. An error in a value, like an unterminated quote, is an
+ * *IniError returned with its token, and scanning can go on. An error
+ * of the reader, or a line longer than the maximum, ends the input.
+ */
+func (s *Scanner) Next() (Token, error) {
+	tok, _, ok := s.d.token()
+	if ok {
+		tok.Raw = s.d.rawText()
+	}
+	err := s.d.savedError
+	s.d.savedError = nil
+
+	if err != nil {
+		return tok, err
+	} else if !ok {
+		return Token{}, io.EOF
+	}
+	return tok, nil
+}
+
+/*
+ * Reads the next logical line as a token, and its value before
+ * unquoting. Headers are returned without brackets, and values are
+ * unquoted, except heredocs, which are taken literally. Names are not
+ * folded. Raw is left empty, as decoding doesn't need it, see rawText.
+ */
+func (d *decodeState) token() (Token, string, bool) {
+	if !d.scan() {
+		return Token{}, "", false
+	}
+
+	kind, name, value := d.dialect.classify(strings.TrimSpace(d.line))
+	if d.heredoc {
+		kind, name, value = lineKeyValue, d.key, d.value
+	}
+	raw := value
+
+	switch {
+	case kind == lineHeader && isBracketed(name):
+		name = strings.TrimSpace(name[1 : len(name)-1])
+
+	case kind == lineKeyValue && !d.heredoc:
+		v, err := unquote(value, &d.dialect)
+		if err != nil {
+			d.saveError(&IniError{d.lineNum, d.line, err.Error()})
+		}
+		value = v
+	}
+
+	return Token{
+		Kind:   TokenKind(kind),
+		Line:   d.lineNum,
+		Offset: d.lines[0].start,
+		End:    d.lines[len(d.lines)-1].end,
+		Name:   name,
+		Value:  value,
+	}, raw, true
+}
+
+// Returns the physical lines of the last token read, joined by
+// newlines.
+func (d *decodeState) rawText() string {
+	if len(d.lines) == 1 {
+		return d.lines[0].text
+	}
+
+	var b strings.Builder
+	for i, p := range d.lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+// A physical line and its byte offsets.
+type physical struct {
+	text       string
+	start, end int64
+}
@@ -0,0 +1,94 @@
+package ini
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	text := "; songs\r\n" +
+		"[CREATE SONG]\r\n" +
+		"Title = Long Way to Go\r\n" +
+		"\r\n" +
+		"Notes <<EOT\n" +
+		"first\n" +
+		"EOT\n" +
+		"extension[]=foo.so\n" +
+		"End Schedule"
+
+	dl := DefaultDialect
+	dl.Continuation = ContinueHeredoc
+	s := NewScanner(strings.NewReader(text), WithDialect(dl))
+	var toks []Token
+	for {
+		tok, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		toks = append(toks, tok)
+	}
+
+	if len(toks) != 7 {
+		t.Fatal("Incorrect number of tokens,", toks)
+	}
+
+	kinds := []TokenKind{TokenComment, TokenSectionHeader, TokenKeyValue, TokenBlank, TokenKeyValue, TokenKeyValue,
+		TokenSectionHeader}
+	for i, kind := range kinds {
+		if toks[i].Kind != kind {
+			t.Fatal("Token", i, "should be", kind, "not", toks[i].Kind)
+		}
+	}
+
+	if toks[1].Name != "CREATE SONG" || toks[1].Line != 2 || toks[1].Offset != 9 || toks[1].End != 22 {
+		t.Fatal("Header token incorrect,", toks[1])
+	} else if toks[2].Name != "Title" || toks[2].Value != "Long Way to Go" || toks[2].Raw != "Title = Long Way to Go" {
+		t.Fatal("Key token incorrect,", toks[2])
+	} else if text[toks[2].Offset:toks[2].End] != toks[2].Raw {
+		t.Fatal("Key token offsets incorrect,", toks[2].Offset, toks[2].End)
+	} else if toks[4].Line != 5 || toks[4].Value != "first" || toks[4].Raw != "Notes <<EOT\nfirst\nEOT" {
+		t.Fatal("Heredoc token incorrect,", toks[4])
+	} else if text[toks[4].Offset:toks[4].End] != toks[4].Raw {
+		t.Fatal("Heredoc token offsets incorrect,", toks[4].Offset, toks[4].End)
+	} else if toks[5].Name != "extension[]" || toks[5].Line != 8 {
+		t.Fatal("Array key token incorrect,", toks[5])
+	} else if toks[6].Name != "End Schedule" || toks[6].End != int64(len(text)) {
+		t.Fatal("Unbracketed header token incorrect,", toks[6])
+	}
+
+	text = "[a]\nName=\"x\n  more\nEnd=1\n"
+	dl = PythonDialect
+	dl.Quoting = QuoteEscape
+	s = NewScanner(strings.NewReader(text), WithDialect(dl))
+	s.Next()
+	tok, err := s.Next()
+	if err == nil || !strings.HasPrefix(err.Error(), "Invalid quoted value on line 2") {
+		t.Fatal("Expected quote error,", err)
+	} else if tok.Raw != "Name=\"x\n  more" || text[tok.Offset:tok.End] != tok.Raw {
+		t.Fatal("Continued token incorrect,", tok)
+	}
+
+	tok, err = s.Next()
+	if err != nil || tok.Name != "End" || tok.Line != 4 {
+		t.Fatal("Expected to scan on after an error,", tok, err)
+	} else if _, err := s.Next(); err != io.EOF {
+		t.Fatal("Expected EOF,", err)
+	}
+
+	s = NewScanner(strings.NewReader("Name=x\nBare line\n"), WithDialect(PHPDialect))
+	s.Next()
+	if tok, _ := s.Next(); tok.Kind != TokenBareLine || tok.Name != "Bare line" {
+		t.Fatal("Expected bare line,", tok)
+	}
+
+	s = NewScanner(strings.NewReader("Name="+strings.Repeat("x", 100)), WithMaxLineLength(50))
+	_, err = s.Next()
+	var iniErr *IniError
+	if !errors.As(err, &iniErr) || err.Error() != `Line longer than 50 bytes on line 1: ""` {
+		t.Fatal("Expected line too long,", err)
+	}
+}
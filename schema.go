@@ -0,0 +1,306 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+/*
+ * JSONSchema returns a JSON Schema describing the values Unmarshal
+ * decodes into v, a struct or a pointer to one, which may be nil.
+ * Sections are objects and repeated sections arrays of objects, named
+ * by their tag without brackets, like keys. Repeated keys are arrays,
+ * map fields objects of their entries, and scalars strings, booleans,
+ * integers or numbers.
+ *
+ * Integers must fit the size of their field, unsigned ones must not be
+ * negative, and arrays have their fixed length, as Unmarshal checks.
+ * The non-zero keys of v are defaults, as in Template, and the comment
+ * or doc tag of a field is its description. Unknown keys are allowed,
+ * since Unmarshal leaves them unmatched.
+ *
+ * Tags add constraints for validators, which Unmarshal doesn't check:
+ *
+ *	Port  int    `ini:"port,required" default:"8080" min:"1" max:"65535"`
+ *	Level string `ini:"level" enum:"debug,info,warn"`
+ *
+ * The "required" option lists a key or section as required by the
+ * section holding it. The default, min, max and enum tags of a key are
+ * read as values of its type, and a list applies min, max and enum to
+ * its elements. A non-zero value in v wins over a default tag.
+ */
+func JSONSchema(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			if rv.Kind() == reflect.Interface {
+				return nil, &IniError{0, "", "Can't describe nil value"}
+			}
+			rv = reflect.Zero(rv.Type().Elem())
+			continue
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, &IniError{0, "", fmt.Sprintf("Can't describe value of type %s", rv.Kind())}
+	}
+
+	s, err := structSchema(rv, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.Title = rv.Type().Name()
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// A jsonSchema is the part of JSON Schema used to describe a struct.
+type jsonSchema struct {
+	Schema               string            `json:"$schema,omitempty"`
+	Title                string            `json:"title,omitempty"`
+	Description          string            `json:"description,omitempty"`
+	Type                 string            `json:"type"`
+	Properties           *schemaProperties `json:"properties,omitempty"`
+	AdditionalProperties *jsonSchema       `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema       `json:"items,omitempty"`
+	MinItems             *int              `json:"minItems,omitempty"`
+	MaxItems             *int              `json:"maxItems,omitempty"`
+	Minimum              interface{}       `json:"minimum,omitempty"`
+	Maximum              interface{}       `json:"maximum,omitempty"`
+	Enum                 []interface{}     `json:"enum,omitempty"`
+	Default              interface{}       `json:"default,omitempty"`
+	Required             []string          `json:"required,omitempty"`
+}
+
+// schemaProperties holds the properties of an object in the order of
+// the fields, which a map would lose.
+type schemaProperties struct {
+	names   []string
+	schemas []*jsonSchema
+}
+
+func (p *schemaProperties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, name := range p.names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		s, err := json.Marshal(p.schemas[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(s)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+/*
+ * Returns the schema of a section: an object with a property for each
+ * field, found the way generateMap finds them. The fields of a nil
+ * embedded struct are described with zero values. The types of the
+ * sections being described are kept in open, to stop at a type that
+ * contains itself.
+ */
+func structSchema(v reflect.Value, open map[reflect.Type]bool) (*jsonSchema, error) {
+	t := v.Type()
+	if open[t] {
+		return nil, &IniError{0, "", fmt.Sprintf("Can't describe recursive type %s", t)}
+	}
+	open[t] = true
+	defer delete(open, t)
+
+	s := &jsonSchema{Type: "object", Properties: &schemaProperties{}}
+	for _, tf := range cachedTypeFields(t, "ini") {
+		f, ok := fieldByIndex(v, tf.index, false)
+		if !ok {
+			f = reflect.Zero(t.FieldByIndex(tf.index).Type)
+		}
+
+		fs, err := fieldSchema(f, open)
+		if err != nil {
+			return nil, err
+		}
+		fs.Description = tf.doc
+
+		name := tf.name
+		if isBracketed(name) {
+			name = name[1 : len(name)-1]
+		}
+		if err := tagConstraints(fs, t.FieldByIndex(tf.index), name); err != nil {
+			return nil, err
+		}
+		if tf.opts.Contains("required") {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties.names = append(s.Properties.names, name)
+		s.Properties.schemas = append(s.Properties.schemas, fs)
+	}
+	return s, nil
+}
+
+// Returns the schema of a field, with the value of a key as its default
+// when it isn't zero.
+func fieldSchema(v reflect.Value, open map[reflect.Type]bool) (*jsonSchema, error) {
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Struct:
+		return structSchema(v, open)
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		items, err := structSchema(reflect.New(t.Elem()).Elem(), open)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "array", Items: items}, nil
+	}
+
+	s, err := typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	if empty := (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && v.Len() == 0; !v.IsZero() && !empty {
+		s.Default = v.Interface()
+		if t == rawValueType {
+			s.Default = string(v.Bytes())
+		}
+	}
+	return s, nil
+}
+
+/*
+ * Adds the default, min, max and enum tags of a field to its schema.
+ * The tags are read as values of the type of the key, or of the
+ * elements of a list, and are an error on a section.
+ */
+func tagConstraints(s *jsonSchema, sf reflect.StructField, name string) error {
+	t, elem := sf.Type, s
+	for isList(t) {
+		t, elem = t.Elem(), elem.Items
+	}
+
+	for _, key := range []string{"default", "min", "max", "enum"} {
+		tag, ok := sf.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		invalid := &IniError{0, "", fmt.Sprintf("Invalid %s tag %q of %s", key, tag, name)}
+		if t.Kind() == reflect.Struct || (key == "default" && elem != s) {
+			return invalid // a section, or the default of a list
+		}
+
+		var values []interface{}
+		parts := []string{tag}
+		if key == "enum" {
+			parts = strings.Split(tag, ",")
+		}
+		for _, part := range parts {
+			v, ok := tagValue(t, strings.TrimSpace(part))
+			if !ok {
+				return invalid
+			}
+			values = append(values, v)
+		}
+
+		switch key {
+		case "default":
+			if s.Default == nil {
+				s.Default = values[0]
+			}
+		case "min", "max":
+			if elem.Type != "integer" && elem.Type != "number" {
+				return invalid
+			} else if key == "min" {
+				elem.Minimum = values[0]
+			} else {
+				elem.Maximum = values[0]
+			}
+		case "enum":
+			elem.Enum = values
+		}
+	}
+	return nil
+}
+
+// Returns a tag read as a value of type t, the way Unmarshal reads it.
+func tagValue(t reflect.Type, s string) (interface{}, bool) {
+	var d decodeState
+	v := reflect.New(t).Elem()
+	d.setValue(v, s)
+	if d.savedError != nil {
+		return nil, false
+	} else if t == rawValueType {
+		return string(v.Bytes()), true
+	}
+	return v.Interface(), true
+}
+
+// Returns the schema of the values of a key.
+func typeSchema(t reflect.Type) (*jsonSchema, error) {
+	if t == rawValueType {
+		return &jsonSchema{Type: "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}, nil
+
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s := &jsonSchema{Type: "integer"}
+		if bits := t.Bits(); bits < 64 {
+			s.Minimum = int64(-1) << (bits - 1)
+			s.Maximum = int64(1)<<(bits-1) - 1
+		}
+		return s, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s := &jsonSchema{Type: "integer", Minimum: 0}
+		if bits := t.Bits(); bits < 64 {
+			s.Maximum = uint64(math.MaxUint64) >> (64 - bits)
+		}
+		return s, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := &jsonSchema{Type: "array", Items: items}
+		if t.Kind() == reflect.Array {
+			n := t.Len()
+			s.MinItems, s.MaxItems = &n, &n
+		}
+		return s, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, &IniError{0, "", fmt.Sprintf("Can't describe map with key of type %s", t.Key().Kind())}
+		}
+		values, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: values}, nil
+	}
+
+	return nil, &IniError{0, "", fmt.Sprintf("Can't describe value of type %s", t.Kind())}
+}
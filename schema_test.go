@@ -0,0 +1,220 @@
+package ini
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type schemaAuth struct {
+	User string
+}
+
+type schemaServer struct {
+	*schemaAuth
+	Name    string            `comment:"Name shown in logs"`
+	Port    uint16            `ini:"port"`
+	Retries int8              `ini:"retries"`
+	Ratio   float64           `ini:"ratio"`
+	Debug   bool              `ini:"debug"`
+	Admins  []string          `ini:"admin"`
+	Pair    [2]int            `ini:"pair,split=:"`
+	Limits  map[string]int64  `ini:"limits"`
+	Labels  map[string]string `ini:"labels"`
+	Raw     RawValue          `ini:"raw"`
+	Secret  string            `ini:"-"`
+	Backend []struct {
+		Address string
+	} `ini:"[backend]" doc:"A server requests are sent to"`
+	TLS struct {
+		Cert string
+	} `ini:"[tls]"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	b, err := JSONSchema(&schemaServer{Name: "api", Port: 8080, Labels: map[string]string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "schemaServer",
+  "type": "object",
+  "properties": {
+    "User": {
+      "type": "string"
+    },
+    "Name": {
+      "description": "Name shown in logs",
+      "type": "string",
+      "default": "api"
+    },
+    "port": {
+      "type": "integer",
+      "minimum": 0,
+      "maximum": 65535,
+      "default": 8080
+    },
+    "retries": {
+      "type": "integer",
+      "minimum": -128,
+      "maximum": 127
+    },
+    "ratio": {
+      "type": "number"
+    },
+    "debug": {
+      "type": "boolean"
+    },
+    "admin": {
+      "type": "array",
+      "items": {
+        "type": "string"
+      }
+    },
+    "pair": {
+      "type": "array",
+      "items": {
+        "type": "integer"
+      },
+      "minItems": 2,
+      "maxItems": 2
+    },
+    "limits": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer"
+      }
+    },
+    "labels": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "string"
+      }
+    },
+    "raw": {
+      "type": "string"
+    },
+    "backend": {
+      "description": "A server requests are sent to",
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "Address": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "tls": {
+      "type": "object",
+      "properties": {
+        "Cert": {
+          "type": "string"
+        }
+      }
+    }
+  }
+}`
+	if string(b) != want {
+		t.Fatalf("Schema written incorrectly,\n%s", b)
+	} else if !json.Valid(b) {
+		t.Fatal("Schema is not valid JSON")
+	}
+
+	if _, err := JSONSchema((*schemaServer)(nil)); err != nil {
+		t.Fatal("Expected schema of a nil pointer,", err)
+	}
+
+	type node struct {
+		Name     string
+		Children []node `ini:"[child]"`
+	}
+	if _, err := JSONSchema(node{}); err == nil || err.Error() != "Can't describe recursive type ini.node" {
+		t.Fatal("Expected recursive type error,", err)
+	}
+
+	var ptr struct {
+		Port *int
+	}
+	if _, err := JSONSchema(ptr); err == nil || err.Error() != "Can't describe value of type ptr" {
+		t.Fatal("Expected pointer error,", err)
+	}
+}
+
+func TestJSONSchemaTags(t *testing.T) {
+	type tagged struct {
+		Port  int     `ini:"port,required" default:"8080" min:"1" max:"65535"`
+		Level string  `ini:"level" enum:"debug, info,warn" default:"info"`
+		Ratio float64 `ini:"ratio" min:"0.5" default:"0.75"`
+		Codes []uint8 `ini:"code" min:"10" enum:"10,20"`
+		Name  string  `ini:"name" default:"x"`
+		DB    struct {
+			Host string `ini:"host,required"`
+		} `ini:"[db],required"`
+	}
+
+	b, err := JSONSchema(&tagged{Name: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var s struct {
+		Properties map[string]struct {
+			Minimum    interface{}
+			Maximum    interface{}
+			Enum       []interface{}
+			Default    interface{}
+			Required   []string
+			Items      map[string]interface{}
+			Properties map[string]interface{}
+		}
+		Required []string
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	p := s.Properties
+	if fmt.Sprint(s.Required) != "[port db]" || fmt.Sprint(p["db"].Required) != "[host]" {
+		t.Fatal("Required keys incorrect,", s.Required, p["db"].Required)
+	} else if p["port"].Default != 8080.0 || p["port"].Minimum != 1.0 || p["port"].Maximum != 65535.0 {
+		t.Fatal("Port constraints incorrect,", p["port"])
+	} else if fmt.Sprint(p["level"].Enum) != "[debug info warn]" || p["level"].Default != "info" {
+		t.Fatal("Enum incorrect,", p["level"])
+	} else if p["ratio"].Minimum != 0.5 || p["ratio"].Default != 0.75 {
+		t.Fatal("Float constraints incorrect,", p["ratio"])
+	} else if items := p["code"].Items; items["minimum"] != 10.0 || items["maximum"] != 255.0 || fmt.Sprint(items["enum"]) != "[10 20]" {
+		t.Fatal("List constraints should apply to the elements,", items)
+	} else if p["name"].Default != "api" {
+		t.Fatal("Value should win over the default tag,", p["name"])
+	}
+
+	var badDefault struct {
+		Port int `ini:"port" default:"eighty"`
+	}
+	var badMin struct {
+		Name string `min:"1"`
+	}
+	var listDefault struct {
+		Hosts []string `ini:"host" default:"a"`
+	}
+	var sectionEnum struct {
+		DB struct{ Host string } `ini:"[db]" enum:"a"`
+	}
+	for _, c := range []struct {
+		v   interface{}
+		err string
+	}{
+		{&badDefault, `Invalid default tag "eighty" of port`},
+		{&badMin, `Invalid min tag "1" of Name`},
+		{&listDefault, `Invalid default tag "a" of host`},
+		{&sectionEnum, `Invalid enum tag "a" of db`},
+	} {
+		if _, err := JSONSchema(c.v); err == nil || err.Error() != c.err {
+			t.Fatal("Expected tag error,", c.err, err)
+		}
+	}
+}
@@ -1,47 +1,59 @@
 package ini
 
-// NewStack returns a new stack.
-func NewStack() *Stack {
-	return &Stack{}
+// NewStack returns a new stack of any values.
+func NewStack() *Stack[interface{}] {
+	return &Stack[interface{}]{}
 }
 
 // Stack is a basic LIFO stack that resizes as needed.
-type Stack struct {
-	items []interface{}
+type Stack[T any] struct {
+	items []T
 	count int
 }
 
 // Push adds an iterm to the top of the stack
-func (s *Stack) Push(item interface{}) {
+func (s *Stack[T]) Push(item T) {
 	s.items = append(s.items[:s.count], item)
 	s.count++
 }
 
-// Pop removes the top item (LIFO) from the stack
-func (s *Stack) Pop() interface{} {
+// Pop removes the top item (LIFO) from the stack, or returns the zero
+// value of T when it is empty
+func (s *Stack[T]) Pop() T {
+	var zero T
 	if s.count == 0 {
-		return nil
+		return zero
 	}
 
 	s.count--
-	return s.items[s.count]
+	item := s.items[s.count]
+	s.items[s.count] = zero // don't keep popped items alive
+	return item
 }
 
-// Peek returns item at top of stack without removing it
-func (s *Stack) Peek() interface{} {
+// Peek returns item at top of stack without removing it, or the zero
+// value of T when it is empty
+func (s *Stack[T]) Peek() T {
 	if s.count == 0 {
-		return nil
+		var zero T
+		return zero
 	}
 
 	return s.items[s.count-1]
 }
 
+// Index returns the item i places from the bottom of the stack, where
+// the item at Size()-1 is the top
+func (s *Stack[T]) Index(i int) T {
+	return s.items[i]
+}
+
 // Empty returns true when stack is empty, false otherwise
-func (s *Stack) Empty() bool {
+func (s *Stack[T]) Empty() bool {
 	return s.count == 0
 }
 
 // Size returns the number of items in the stack
-func (s *Stack) Size() int {
+func (s *Stack[T]) Size() int {
 	return s.count
 }
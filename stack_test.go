@@ -80,3 +80,25 @@ func TestStack(t *testing.T) {
 	}
 
 }
+
+func TestTypedStack(t *testing.T) {
+	var s Stack[string]
+
+	if s.Pop() != "" || s.Peek() != "" {
+		t.Fatal("Empty stack did not return the zero value")
+	}
+
+	s.Push("a")
+	s.Push("b")
+	if s.Size() != 2 || s.Peek() != "b" {
+		t.Fatal("Stack peek did not return expected result")
+	}
+
+	if s.Index(0) != "a" || s.Index(1) != "b" {
+		t.Fatal("Stack index did not return expected results")
+	}
+
+	if s.Pop() != "b" || s.Pop() != "a" || !s.Empty() {
+		t.Fatal("Stack pops did not return expected results")
+	}
+}
@@ -0,0 +1,68 @@
+package ini
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// tagOptions is the string following a comma in a struct field's "ini"
+// tag, or the empty string. It does not include the leading comma.
+type tagOptions string
+
+// parseTag splits a struct field's ini tag into its name and
+// comma-separated options.
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// Contains reports whether a comma-separated list of options
+// contains a particular optionName flag. optionName must be
+// surrounded by a string boundary or commas.
+func (o tagOptions) Contains(optionName string) bool {
+	for _, opt := range o.list() {
+		if opt == optionName {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of an option written as name=value, and whether
+// it is present. The value is at least one character long, so it may be
+// a comma itself, as in "split=,".
+func (o tagOptions) Get(name string) (string, bool) {
+	for _, opt := range o.list() {
+		if strings.HasPrefix(opt, name+"=") {
+			return opt[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// Splits the options at commas, except for the first character of the
+// value of a name=value option.
+func (o tagOptions) list() []string {
+	var opts []string
+	s := string(o)
+	for s != "" {
+		i := strings.Index(s, ",")
+		if eq := strings.Index(s, "="); eq >= 0 && eq+1 < len(s) && (i < 0 || eq < i) {
+			// skip the first character of the value
+			_, n := utf8.DecodeRuneInString(s[eq+1:])
+			i = strings.Index(s[eq+1+n:], ",")
+			if i >= 0 {
+				i += eq + 1 + n
+			}
+		}
+		if i < 0 {
+			opts = append(opts, s)
+			break
+		}
+		opts = append(opts, s[:i])
+		s = s[i+1:]
+	}
+	return opts
+}
@@ -0,0 +1,92 @@
+package ini
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+ * Template returns a sample INI file for v, a struct or a pointer to
+ * one, with every key and section Unmarshal decodes into it, for
+ * example configs that follow the struct they are read into.
+ *
+ * Keys are written with the values of v, so a struct holding the
+ * defaults documents them. A zero key with a default tag, like
+ * `default:"8080"` as read by JSONSchema, is written with the default.
+ * The comment or doc tag of a field, like `comment:"Port to listen on"`,
+ * is written as comment lines before its key or header. An empty slice
+ * or map is written with one zero entry, and a slice of structs as one
+ * section, its first element or a zero one, marked as repeatable.
+ */
+func Template(v interface{}) ([]byte, error) {
+	e := encodeState{dialect: DefaultDialect, template: true}
+	if err := e.marshal(v); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// Writes a section of a template, the first element of a slice of
+// structs standing for all of them.
+func (e *encodeState) writeExampleSection(f encodeField, name, path string) {
+	v := f.value
+	if v.Kind() == reflect.Slice {
+		if v.Len() > 0 {
+			v = v.Index(0)
+		} else {
+			v = reflect.New(v.Type().Elem()).Elem()
+		}
+	}
+
+	if e.Len() > 0 {
+		e.WriteByte('\n')
+	}
+	e.writeComment(f.doc)
+	if f.value.Kind() == reflect.Slice {
+		e.writeComment("[" + name + "] may be repeated")
+	}
+	fmt.Fprintf(e, "[%s]\n", name)
+	e.writeKeys(v)
+	e.writeSections(v, path)
+}
+
+// Writes the lines of a comment, if any.
+func (e *encodeState) writeComment(s string) {
+	if len(s) == 0 {
+		return
+	}
+	for _, line := range strings.Split(s, "\n") {
+		fmt.Fprintf(e, "%s %s\n", e.dialect.CommentPrefixes[0], line)
+	}
+}
+
+// Returns the default tag of a zero key as the value to write. The tag
+// must be read by Unmarshal as a value of the key, which can't be a list
+// or map.
+func (e *encodeState) defaultValue(f encodeField) reflect.Value {
+	t := f.value.Type()
+	if !isList(t) && t.Kind() != reflect.Map {
+		if _, ok := tagValue(t, f.def); ok {
+			return reflect.ValueOf(f.def)
+		}
+	}
+	e.saveError(&IniError{0, "", fmt.Sprintf("Invalid default tag %q of %s", f.def, f.name)})
+	return f.value
+}
+
+// Returns a list or map holding one zero element when v is empty, so a
+// template shows how its key is written. A list with the split option
+// is left empty, written as an empty value.
+func exampleValue(v reflect.Value, opts tagOptions) reflect.Value {
+	_, split := opts.Get("split")
+	switch {
+	case v.Kind() == reflect.Slice && v.Type() != rawValueType && v.Len() == 0 && !split:
+		return reflect.MakeSlice(v.Type(), 1, 1)
+	case v.Kind() == reflect.Map && v.Len() == 0 && v.Type().Key().Kind() == reflect.String:
+		m := reflect.MakeMap(v.Type())
+		m.SetMapIndex(reflect.ValueOf("key").Convert(v.Type().Key()), reflect.Zero(v.Type().Elem()))
+		return m
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+package ini
+
+import (
+	"testing"
+)
+
+type templateServer struct {
+	Name    string            `comment:"Name shown in logs"`
+	Port    int               `ini:"port" doc:"Port to listen on"`
+	Admins  []string          `comment:"May be set more than once"`
+	Limits  map[string]int    `ini:"limits"`
+	Labels  map[string]string `ini:"labels"`
+	Secret  string            `ini:"-" comment:"never written"`
+	Backend []struct {
+		Address string `comment:"host:port\nof the backend"`
+		Weight  int
+	} `ini:"[backend]" comment:"A server requests are sent to"`
+	TLS struct {
+		Cert string
+	} `ini:"[tls]"`
+}
+
+func TestTemplate(t *testing.T) {
+	s := templateServer{Name: "api", Port: 8080}
+	s.Labels = map[string]string{"team": "core"}
+
+	b, err := Template(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `; Name shown in logs
+Name=api
+; Port to listen on
+port=8080
+; May be set more than once
+Admins=
+limits[key]=0
+labels[team]=core
+
+; A server requests are sent to
+; [backend] may be repeated
+[backend]
+; host:port
+; of the backend
+Address=
+Weight=0
+
+[tls]
+Cert=
+`
+	if string(b) != want {
+		t.Fatalf("Template written incorrectly,\n%s", b)
+	}
+
+	var back templateServer
+	if err := Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	} else if back.Port != 8080 || len(back.Backend) != 1 || back.Limits["key"] != 0 || back.Labels["team"] != "core" {
+		t.Fatal("Template read back incorrectly,", back)
+	}
+
+	var split struct {
+		Hosts []string `ini:"hosts,split=,"`
+		Ports []int    `ini:"ports,split=:"`
+	}
+	if b, err := Template(&split); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "hosts=\nports=\n" {
+		t.Fatalf("Template of split lists incorrect,\n%s", b)
+	}
+
+	var defaults struct {
+		Host  string `default:"localhost"`
+		Port  int    `ini:"port" default:"8080"`
+		Debug bool   `default:"yes"`
+	}
+	defaults.Port = 9090
+	if b, err := Template(&defaults); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "Host=localhost\nport=9090\nDebug=yes\n" {
+		t.Fatalf("Template of default tags incorrect,\n%s", b)
+	}
+
+	var invalid struct {
+		Port int `ini:"port" default:"high"`
+	}
+	if _, err := Template(&invalid); err == nil || err.Error() != `Invalid default tag "high" of port` {
+		t.Fatal("Expected error for invalid default tag,", err)
+	}
+
+	if _, err := Template(3); err == nil || err.Error() != "Can't encode value of type int" {
+		t.Fatal("Expected error for a value that isn't a struct,", err)
+	}
+}
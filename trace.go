@@ -0,0 +1,99 @@
+package ini
+
+import (
+	"context"
+	"log/slog"
+)
+
+// TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+const (
+	// TraceLine is a logical line read from the file.
+	TraceLine TraceKind = iota + 1
+
+	// TraceHeader is a header that entered a section.
+	TraceHeader
+
+	// TracePush is a section scope pushed on the stack of sections.
+	TracePush
+
+	// TracePop is a section scope popped off the stack, looking for the
+	// section of a header in an enclosing one.
+	TracePop
+
+	// TraceSet is a value set into a field, read from the file or from
+	// a DEFAULT or base section.
+	TraceSet
+
+	// TraceUnmatched is a line that matched no field.
+	TraceUnmatched
+)
+
+var traceKindNames = []string{"", "line", "header", "push", "pop", "set", "unmatched"}
+
+func (k TraceKind) String() string {
+	if k > 0 && int(k) < len(traceKindNames) {
+		return traceKindNames[k]
+	}
+	return "unknown"
+}
+
+// A TraceEvent describes a step of decoding. Line and Text are the line
+// being decoded, Section the current section. Key and Value are set for
+// TraceSet, and Depth, the number of scopes on the stack afterwards, for
+// TracePush and TracePop.
+type TraceEvent struct {
+	Kind    TraceKind
+	Line    int
+	Text    string
+	Section string
+	Key     string
+	Value   string
+	Depth   int
+}
+
+// A Tracer receives the steps of decoding, to debug how lines are
+// matched to sections and fields.
+type Tracer interface {
+	Trace(ev TraceEvent)
+}
+
+// TracerFunc is a function that is a Tracer.
+type TracerFunc func(ev TraceEvent)
+
+// Trace calls f(ev).
+func (f TracerFunc) Trace(ev TraceEvent) {
+	f(ev)
+}
+
+// SlogTracer returns a Tracer that logs every event at debug level.
+func SlogTracer(logger *slog.Logger) Tracer {
+	return TracerFunc(func(ev TraceEvent) {
+		ctx := context.Background()
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return
+		}
+
+		attrs := []slog.Attr{slog.Int("line", ev.Line), slog.String("text", ev.Text)}
+		if len(ev.Section) > 0 {
+			attrs = append(attrs, slog.String("section", ev.Section))
+		}
+		switch ev.Kind {
+		case TraceSet:
+			attrs = append(attrs, slog.String("key", ev.Key), slog.String("value", ev.Value))
+		case TracePush, TracePop:
+			attrs = append(attrs, slog.Int("depth", ev.Depth))
+		}
+		logger.LogAttrs(ctx, slog.LevelDebug, ev.Kind.String(), attrs...)
+	})
+}
+
+// Sends an event about the current line to the tracer, if any.
+func (d *decodeState) trace(ev TraceEvent) {
+	if d.tracer == nil {
+		return
+	}
+	ev.Line, ev.Text, ev.Section = d.lineNum, d.line, d.section.name
+	d.tracer.Trace(ev)
+}
@@ -0,0 +1,79 @@
+package ini
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	var d struct {
+		Name  string
+		Songs []struct {
+			Title string
+		} `ini:"[song]"`
+		Mysql struct {
+			Host string
+		} `ini:"[mysql]"`
+	}
+
+	b := []byte(`
+Name=Tunes
+[song]
+Title=One
+[mysql]
+Host=localhost
+Port=3306
+`)
+
+	var events []TraceEvent
+	tracer := TracerFunc(func(ev TraceEvent) { events = append(events, ev) })
+
+	err := UnmarshalWith(b, &d, WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	for _, ev := range events {
+		kinds = append(kinds, ev.Kind.String())
+	}
+	want := "line line set line push header line set line pop push header line set line unmatched"
+	if strings.Join(kinds, " ") != want {
+		t.Fatal("Trace events incorrect,", kinds)
+	}
+
+	var set, pop TraceEvent
+	for _, ev := range events {
+		if ev.Kind == TraceSet && ev.Key == "host" {
+			set = ev
+		} else if ev.Kind == TracePop {
+			pop = ev
+		}
+	}
+
+	if set.Line != 6 || set.Text != "Host=localhost" || set.Section != "mysql" || set.Value != "localhost" {
+		t.Fatal("Set event incorrect,", set)
+	} else if pop.Line != 5 || pop.Depth != 1 {
+		t.Fatal("Pop event incorrect,", pop)
+	}
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	err = UnmarshalWith(b, &d, WithTracer(SlogTracer(logger)))
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(log.String(), `msg=set line=6 text="Host=localhost" section=mysql key=host value=localhost`) {
+		t.Fatal("Set event not logged,", log.String())
+	}
+
+	log.Reset()
+	logger = slog.New(slog.NewTextHandler(&log, nil))
+	err = UnmarshalWith(b, &d, WithTracer(SlogTracer(logger)))
+	if err != nil {
+		t.Fatal(err)
+	} else if log.Len() > 0 {
+		t.Fatal("Events logged below debug level,", log.String())
+	}
+}